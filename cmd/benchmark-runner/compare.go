@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"database-benchmark/internal/compare"
+	"database-benchmark/internal/output"
+	"database-benchmark/internal/store"
+)
+
+// compareCommand compares a candidate result against a baseline and fails
+// when a gated metric regressed by more than the thresholds allow. Each
+// argument is a run directory or a file written with --output=json.
+func compareCommand(args []string, logger *log.Logger) int {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	maxRegression := fs.Float64("max-regression", 0.05, "fraction by which throughput may fall or latency may rise")
+	maxErrorRate := fs.Float64("max-error-rate-increase", 0.001, "absolute amount by which the error rate may rise")
+	gate := fs.String("gate", strings.Join(compare.DefaultGate, ","), "comma-separated metrics that fail the comparison ("+strings.Join(compare.Metrics, ", ")+")")
+	alpha := fs.Float64("alpha", 0.05, "significance level of the throughput difference test")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "usage: benchmark-runner compare [flags] <baseline> <candidate>")
+		return 2
+	}
+	thresholds := compare.Thresholds{
+		MaxRegression:        *maxRegression,
+		MaxErrorRateIncrease: *maxErrorRate,
+		Gate:                 splitList(*gate),
+	}
+	for _, metric := range thresholds.Gate {
+		if !slices.Contains(compare.Metrics, metric) {
+			logger.Printf("Unknown metric in --gate: %s", metric)
+			return 2
+		}
+	}
+
+	baseline, err := store.Load(fs.Arg(0))
+	if err != nil {
+		logger.Printf("Failed to load baseline: %v", err)
+		return 2
+	}
+	candidate, err := store.Load(fs.Arg(1))
+	if err != nil {
+		logger.Printf("Failed to load candidate: %v", err)
+		return 2
+	}
+
+	failed := false
+	for _, pair := range pairRuns(baseline, candidate) {
+		if pair.baseline == nil || pair.candidate == nil {
+			fmt.Printf("%s: missing from the %s\n\n", pair.name, missingSide(pair))
+			failed = true
+			continue
+		}
+		deltas := compare.Compare(pair.baseline.Result, pair.candidate.Result, thresholds)
+		printDeltas(pair.name, deltas)
+		printSignificance(pair, *alpha)
+		if compare.Failed(deltas) {
+			failed = true
+		}
+	}
+	if failed {
+		fmt.Println("FAIL")
+		return 1
+	}
+	fmt.Println("PASS")
+	return 0
+}
+
+// runPair is a baseline run and the candidate run it is compared with.
+type runPair struct {
+	name      string
+	baseline  *output.Run
+	candidate *output.Run
+}
+
+// pairRuns matches the runs of the two sides by test. Two single runs are
+// always compared with each other, so that two databases can be compared.
+func pairRuns(baseline, candidate []output.Run) []runPair {
+	if len(baseline) == 1 && len(candidate) == 1 {
+		name := baseline[0].Name()
+		if other := candidate[0].Name(); other != name {
+			name += " vs " + other
+		}
+		return []runPair{{name: name, baseline: &baseline[0], candidate: &candidate[0]}}
+	}
+
+	var pairs []runPair
+	index := make(map[string]int)
+	for i := range baseline {
+		index[baseline[i].Name()] = len(pairs)
+		pairs = append(pairs, runPair{name: baseline[i].Name(), baseline: &baseline[i]})
+	}
+	for i := range candidate {
+		if j, ok := index[candidate[i].Name()]; ok {
+			pairs[j].candidate = &candidate[i]
+			continue
+		}
+		pairs = append(pairs, runPair{name: candidate[i].Name(), candidate: &candidate[i]})
+	}
+	return pairs
+}
+
+func missingSide(pair runPair) string {
+	if pair.baseline == nil {
+		return "baseline"
+	}
+	return "candidate"
+}
+
+func printDeltas(name string, deltas []compare.Delta) {
+	fmt.Println(name)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METRIC\tBASELINE\tCANDIDATE\tCHANGE\tVERDICT")
+	for _, d := range deltas {
+		var verdict string
+		switch {
+		case d.Regressed && d.Gated:
+			verdict = "REGRESSION"
+		case d.Regressed:
+			verdict = "worse"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", d.Metric, d.Baseline, d.Candidate, d.Change, verdict)
+	}
+	tw.Flush()
+}
+
+// printSignificance says whether the throughput difference of pair is
+// significant at level alpha. It is informational and never fails the
+// comparison.
+func printSignificance(pair runPair, alpha float64) {
+	p, n1, n2, ok := compare.Significance(pair.baseline.Result, pair.candidate.Result)
+	switch {
+	case !ok:
+		fmt.Printf("Too few intervals to test the throughput difference (%d vs %d, need %d each)\n", n1, n2, compare.MinSamples)
+	case p < alpha:
+		fmt.Printf("Throughput difference is significant (Mann-Whitney U, p=%.4f, %d vs %d intervals)\n", p, n1, n2)
+	default:
+		fmt.Printf("Throughput difference is not significant (Mann-Whitney U, p=%.4f, %d vs %d intervals)\n", p, n1, n2)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"database-benchmark/internal/config"
+	"database-benchmark/internal/database"
+	"database-benchmark/internal/output"
+	"database-benchmark/internal/slo"
+	"database-benchmark/internal/store"
+)
+
+// defaultCheckpoint is where a matrix records its progress by default.
+const defaultCheckpoint = "matrix-checkpoint.json"
+
+// cell is one run of a matrix.
+type cell struct {
+	DB          string
+	Workload    string
+	Test        string
+	Concurrency int
+}
+
+func (c cell) String() string {
+	return fmt.Sprintf("%s/%s/%s c=%d", c.DB, c.Workload, c.Test, c.Concurrency)
+}
+
+// cellOutcome is how a completed cell went.
+type cellOutcome struct {
+	Cell string
+	database.Stats
+	// Integrity is the result's Result.Integrity.
+	Integrity string
+	// SLO is "pass" or "FAIL", or empty when the test has no criteria.
+	SLO string `json:",omitempty"`
+	// Dir is the cell's run directory in the store, if it was saved.
+	Dir string `json:",omitempty"`
+}
+
+// checkpoint records the cells of a matrix that completed, so that the
+// matrix can resume after a failure or interrupt.
+type checkpoint struct {
+	// Flags are the effective flag values of the matrix, except for the
+	// ones in resumeIgnored; a matrix only resumes from a checkpoint with
+	// the same flags.
+	Flags map[string]string
+	Done  []cellOutcome
+}
+
+// resumeIgnored lists the flags that do not change the runs of a matrix and
+// may differ when it resumes.
+var resumeIgnored = []string{"checkpoint", "quiet", "progress-interval", "metrics-addr"}
+
+// matrixCommand runs every combination of the selected databases, workloads,
+// tests and concurrency levels, each in isolation like a single run.
+func matrixCommand(ctx context.Context, args []string, logger *log.Logger) int {
+	fs := flag.NewFlagSet("matrix", flag.ContinueOnError)
+	f := bindLoadFlags(fs)
+	dbList := fs.String("db", "", "comma-separated databases (default: matrix.databases in config.yaml, or all)")
+	workloadList := fs.String("workload", "", "comma-separated workloads (default: matrix.workloads in config.yaml, or all)")
+	testList := fs.String("test", "", "comma-separated tests of the selected workloads (default: matrix.tests in config.yaml, or all)")
+	concurrencyList := fs.String("concurrency", "", "comma-separated concurrency levels (default: matrix.concurrency in config.yaml, or benchmark_settings.default_concurrency)")
+	resultsDir := fs.String("results", store.DefaultRoot, "directory to keep a record of every run in (empty = don't)")
+	checkpointFile := fs.String("checkpoint", defaultCheckpoint, "file recording the completed cells, to resume the matrix from")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.LoadConfig("config.yaml")
+	if err != nil {
+		logger.Printf("Failed to load config: %v", err)
+		return 2
+	}
+	cells, err := matrixCells(cfg.Matrix, cfg.BenchmarkSettings.DefaultConcurrency, *dbList, *workloadList, *testList, *concurrencyList)
+	if err != nil {
+		logger.Println(err)
+		return 2
+	}
+
+	cp, err := loadCheckpoint(*checkpointFile)
+	if err != nil {
+		logger.Printf("Failed to load checkpoint: %v", err)
+		return 2
+	}
+	flags := flagValues(fs)
+	for _, name := range resumeIgnored {
+		delete(flags, name)
+	}
+	if cp == nil {
+		cp = &checkpoint{Flags: flags}
+	} else if !maps.Equal(cp.Flags, flags) {
+		logger.Printf("Checkpoint %s is from a matrix with other flags; delete it to start over", *checkpointFile)
+		return 2
+	}
+	done := make(map[string]cellOutcome)
+	for _, outcome := range cp.Done {
+		done[outcome.Cell] = outcome
+	}
+
+	reporter := f.progress()
+	failed := make(map[string]bool)
+	interrupted := false
+	for i, c := range cells {
+		if _, ok := done[c.String()]; ok {
+			reporter.Printf("Cell %d/%d: %s already done", i+1, len(cells), c)
+			continue
+		}
+		if ctx.Err() != nil {
+			interrupted = true
+			break
+		}
+		reporter.Printf("Cell %d/%d: %s", i+1, len(cells), c)
+		outcome, err := runCell(ctx, fs, f, c, *resultsDir, logger)
+		if ctx.Err() != nil {
+			// The cell was cut short and runs again on resume.
+			interrupted = true
+			break
+		}
+		if err != nil {
+			logger.Printf("Cell %s failed: %v", c, err)
+			reporter.Printf("Cell %s failed: %v", c, err)
+			failed[c.String()] = true
+			continue
+		}
+		done[c.String()] = outcome
+		cp.Done = append(cp.Done, outcome)
+		if err := saveCheckpoint(*checkpointFile, cp); err != nil {
+			logger.Printf("Failed to save checkpoint: %v", err)
+			return 1
+		}
+	}
+
+	passed := printMatrix(cells, done, failed)
+	switch {
+	case interrupted:
+		logger.Printf("Matrix interrupted; rerun it with the same arguments to resume from %s", *checkpointFile)
+		return exitInterrupted
+	case len(failed) > 0:
+		logger.Printf("%d cells failed; rerun the matrix with the same arguments to retry them", len(failed))
+		return 1
+	}
+	if err := os.Remove(*checkpointFile); err != nil && !os.IsNotExist(err) {
+		logger.Printf("Failed to remove checkpoint: %v", err)
+	}
+	if !passed {
+		return 1
+	}
+	return 0
+}
+
+// matrixCells resolves the cells of the matrix from the comma-separated flag
+// values, falling back to the matrix section of the config and then to
+// every database, workload and test at defaultLevel, the default
+// concurrency of the config.
+func matrixCells(m config.Matrix, defaultLevel int, dbList, workloadList, testList, concurrencyList string) ([]cell, error) {
+	pick := func(flagValue string, fromConfig []string) []string {
+		if flagValue != "" {
+			return splitList(flagValue)
+		}
+		return fromConfig
+	}
+
+	dbs := pick(dbList, m.Databases)
+	if len(dbs) == 0 {
+		dbs = databases
+	}
+	for _, db := range dbs {
+		if !slices.Contains(databases, db) {
+			return nil, fmt.Errorf("unsupported database type: %s", db)
+		}
+	}
+	selected := pick(workloadList, m.Workloads)
+	for _, workload := range selected {
+		if _, ok := workloads[workload]; !ok {
+			return nil, fmt.Errorf("unsupported workload: %s", workload)
+		}
+	}
+	tests := pick(testList, m.Tests)
+	for _, test := range tests {
+		found := false
+		for workload, byName := range workloads {
+			if _, ok := byName[test]; ok && (len(selected) == 0 || slices.Contains(selected, workload)) {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("test %s is not part of a selected workload", test)
+		}
+	}
+
+	levels := m.Concurrency
+	if concurrencyList != "" {
+		levels = nil
+		for _, item := range splitList(concurrencyList) {
+			level, err := strconv.Atoi(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid concurrency level: %s", item)
+			}
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) == 0 {
+		if defaultLevel == 0 {
+			defaultLevel = defaultConcurrency
+		}
+		levels = []int{defaultLevel}
+	}
+	for _, level := range levels {
+		if level < 1 {
+			return nil, fmt.Errorf("invalid concurrency level: %d", level)
+		}
+	}
+
+	var cells []cell
+	for _, db := range dbs {
+		for _, t := range allTests() {
+			if len(selected) > 0 && !slices.Contains(selected, t.Workload) || len(tests) > 0 && !slices.Contains(tests, t.Test) {
+				continue
+			}
+			for _, level := range levels {
+				cells = append(cells, cell{DB: db, Workload: t.Workload, Test: t.Test, Concurrency: level})
+			}
+		}
+	}
+	return cells, nil
+}
+
+// runCell runs one cell like the run command: connected on its own, with a
+// reset, setup and teardown around it, and saved to the store in root.
+func runCell(ctx context.Context, fs *flag.FlagSet, f *runFlags, c cell, root string, logger *log.Logger) (cellOutcome, error) {
+	f.dbType, f.workloadName, f.testName, f.concurrency = &c.DB, &c.Workload, &c.Test, &c.Concurrency
+	b, err := openBenchmark(f)
+	if err != nil {
+		return cellOutcome{}, err
+	}
+	defer b.driver.Close()
+
+	opts, err := f.options(b.cfg)
+	if err != nil {
+		return cellOutcome{}, err
+	}
+	monitor, stopMonitor, err := f.monitor(b)
+	if err != nil {
+		return cellOutcome{}, err
+	}
+	defer stopMonitor()
+	opts.Monitor = monitor
+
+	serverVersion, err := b.driver.ServerVersion(ctx)
+	if err != nil {
+		logger.Printf("Failed to get database server version: %v", err)
+	}
+	startedAt := time.Now()
+	result, err := b.run(ctx, opts, logger)
+	if err != nil {
+		return cellOutcome{}, err
+	}
+	jsonOutput, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return cellOutcome{}, fmt.Errorf("failed to marshal result: %w", err)
+	}
+	logger.Println(string(jsonOutput))
+	if result.Interrupted {
+		// The cell runs again in full on resume, so its partial result is
+		// neither saved nor checked.
+		return cellOutcome{}, nil
+	}
+
+	outcome := cellOutcome{Cell: c.String(), Stats: result.Stats, Integrity: result.Integrity()}
+	if root != "" {
+		run := output.Run{DB: c.DB, Workload: c.Workload, Test: c.Test, Result: result}
+		flags := flagValues(fs)
+		flags["db"], flags["workload"], flags["test"], flags["concurrency"] = c.DB, c.Workload, c.Test, strconv.Itoa(c.Concurrency)
+		outcome.Dir, err = b.save(root, run, opts, startedAt, serverVersion, cellArgs(fs, c), flags)
+		if err != nil {
+			return cellOutcome{}, fmt.Errorf("failed to save run: %w", err)
+		}
+		logger.Printf("Saved run to %s", outcome.Dir)
+	}
+	if criteria, ok := b.cfg.SLO(c.Workload, c.Test); ok {
+		verdict := slo.Evaluate(c.DB, c.Workload, c.Test, criteria, result)
+		printVerdict(os.Stderr, verdict)
+		outcome.SLO = "pass"
+		if !verdict.Passed() {
+			outcome.SLO = "FAIL"
+		}
+	}
+	return outcome, nil
+}
+
+// cellArgs returns the arguments of the run command that repeats cell with
+// the load flags given to the matrix.
+func cellArgs(fs *flag.FlagSet, c cell) []string {
+	var args []string
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "db", "workload", "test", "concurrency", "checkpoint":
+			return
+		}
+		args = append(args, "--"+fl.Name+"="+fl.Value.String())
+	})
+	return append(args, "--db="+c.DB, "--workload="+c.Workload, "--test="+c.Test, "--concurrency="+strconv.Itoa(c.Concurrency))
+}
+
+// loadCheckpoint reads the checkpoint at path, or returns nil if there is
+// none.
+func loadCheckpoint(path string) (*checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cp, nil
+}
+
+// saveCheckpoint replaces the checkpoint at path, so that an interrupt never
+// leaves it half written.
+func saveCheckpoint(path string, cp *checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// printMatrix prints the outcome of every cell and reports whether all of
+// them completed, passed their integrity checks and met their SLOs.
+func printMatrix(cells []cell, done map[string]cellOutcome, failed map[string]bool) bool {
+	passed := true
+	fmt.Printf("Matrix of %d cells\n", len(cells))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CELL\tOPS/S\tP50\tP99\tERROR RATE\tINTEGRITY\tSLO\tRUN")
+	for _, c := range cells {
+		outcome, ok := done[c.String()]
+		if !ok {
+			passed = false
+			status := "not run"
+			if failed[c.String()] {
+				status = "failed"
+			}
+			fmt.Fprintf(tw, "%s\t\t\t\t\t\t\t%s\n", c, status)
+			continue
+		}
+		sloStatus := outcome.SLO
+		if sloStatus == "" {
+			sloStatus = "-"
+		}
+		if outcome.SLO == "FAIL" || outcome.Integrity == "FAILED" {
+			passed = false
+		}
+		fmt.Fprintf(tw, "%s\t%.1f\t%v\t%v\t%.2f%%\t%s\t%s\t%s\n", c, outcome.Throughput, outcome.P50Latency, outcome.P99Latency,
+			outcome.ErrorRate*100, outcome.Integrity, sloStatus, outcome.Dir)
+	}
+	tw.Flush()
+	return passed
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"database-benchmark/internal/metrics"
+)
+
+// serveMetrics exposes the live metrics of b's test at /metrics on addr. It
+// returns the collector to follow the run with and a function that stops
+// serving.
+func serveMetrics(addr string, b *benchmark) (*metrics.Collector, func(), error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to serve metrics: %w", err)
+	}
+	collector := metrics.NewCollector(map[string]string{
+		"db":       b.dbType,
+		"workload": b.workloadName,
+		"test":     b.testName,
+	})
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", collector)
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	return collector, func() { server.Close() }, nil
+}
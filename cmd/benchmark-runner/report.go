@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"database-benchmark/internal/report"
+	"database-benchmark/internal/store"
+)
+
+// reportCommand writes an HTML report of stored runs. Each argument is a run
+// directory, a directory of runs or a file written with --output=json; without
+// arguments every run in the store is reported.
+func reportCommand(args []string, logger *log.Logger) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	resultsDir := fs.String("results", store.DefaultRoot, "run store to report on when no runs are given")
+	outputFile := fs.String("output-file", "report.html", "file to write the report to")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{*resultsDir}
+	}
+
+	var entries []store.Entry
+	for _, path := range paths {
+		found, err := store.List(path)
+		if err != nil {
+			logger.Printf("Failed to load runs: %v", err)
+			return 2
+		}
+		entries = append(entries, found...)
+	}
+	if len(entries) == 0 {
+		logger.Printf("No runs found in %v", paths)
+		return 2
+	}
+
+	file, err := os.Create(*outputFile)
+	if err != nil {
+		logger.Printf("Failed to create report: %v", err)
+		return 1
+	}
+	if err := report.Write(file, entries, time.Now()); err != nil {
+		file.Close()
+		logger.Printf("Failed to write report: %v", err)
+		return 1
+	}
+	if err := file.Close(); err != nil {
+		logger.Printf("Failed to write report: %v", err)
+		return 1
+	}
+	logger.Printf("Wrote report of %d runs to %s", len(entries), *outputFile)
+	return 0
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"database-benchmark/internal/compare"
+	"database-benchmark/internal/config"
+	"database-benchmark/internal/database"
+	"database-benchmark/internal/output"
+	"database-benchmark/internal/progress"
+	"database-benchmark/internal/runner"
+	"database-benchmark/internal/slo"
+	"database-benchmark/internal/store"
+	"database-benchmark/internal/workloads/analytics"
+	"database-benchmark/internal/workloads/ecommerce"
+	"database-benchmark/internal/workloads/socialmedia"
+)
+
+// exitInterrupted is the conventional exit code after SIGINT.
+const exitInterrupted = 130
+
+// defaultConcurrency is the number of workers when no concurrency is given.
+const defaultConcurrency = 100
+
+// databases lists the supported databases in the order they are reported.
+var databases = []string{"postgres", "mysql", "mongo"}
+
+var workloads = map[string]map[string]database.Workload{
+	"ecommerce": {
+		"order_processing": &ecommerce.OrderProcessingTest{},
+		"inventory_update": &ecommerce.InventoryUpdateTest{},
+		"catalog_filter":   &ecommerce.CatalogFilterTest{},
+	},
+	"socialmedia": {
+		"join_on_read":     &socialmedia.JoinOnReadTest{},
+		"fan_out_on_write": &socialmedia.FanOutOnWriteTest{},
+	},
+	"analytics": {
+		"ingestion":       &analytics.IngestionTest{},
+		"dashboard_query": &analytics.DashboardQueryTest{},
+	},
+}
+
+// runFlags are the flags shared by every command that runs a test.
+type runFlags struct {
+	dbType       *string
+	workloadName *string
+	testName     *string
+	concurrency  *int
+	duration     *time.Duration
+	warmup       *time.Duration
+	cooldown     *time.Duration
+	rate         *float64
+	arrival      *string
+	profileName  *string
+	profileSteps *int
+	interval     *time.Duration
+	retry        *int
+	retryBackoff *time.Duration
+	retryMax     *time.Duration
+	retryOn      *errorClassList
+	opTimeout    *time.Duration
+	seed         *int64
+	thinkTime    *time.Duration
+	thinkDist    *string
+	pacing       *time.Duration
+	metricsAddr  *string
+	quiet        *bool
+	progressTick *time.Duration
+}
+
+func bindRunFlags(fs *flag.FlagSet) *runFlags {
+	f := bindLoadFlags(fs)
+	f.dbType = fs.String("db", "postgres", "database type (postgres, mysql, or mongo)")
+	f.workloadName = fs.String("workload", "ecommerce", "workload to run (ecommerce, socialmedia, or analytics)")
+	f.testName = fs.String("test", "order_processing", "test to run")
+	f.concurrency = fs.Int("concurrency", defaultConcurrency, "number of concurrent requests")
+	return f
+}
+
+// bindLoadFlags binds every run flag except the ones that select the
+// database, test and concurrency, which are left nil.
+func bindLoadFlags(fs *flag.FlagSet) *runFlags {
+	retryOn := errorClassList(slices.Clone(runner.DefaultRetryClasses))
+	fs.Var(&retryOn, "retry-on", "comma-separated error `classes` to retry ("+strings.Join(database.KnownErrorClasses, ", ")+")")
+	return &runFlags{
+		duration:     fs.Duration("duration", 30*time.Second, "duration of the test"),
+		warmup:       fs.Duration("warmup", 0, "duration to run before measuring; excluded from results"),
+		cooldown:     fs.Duration("cooldown", 0, "duration to keep running after measuring; excluded from results"),
+		rate:         fs.Float64("rate", 0, "target operations per second; enables open-loop mode (0 = closed-loop)"),
+		arrival:      fs.String("arrival", runner.ArrivalFixed, "open-loop arrival schedule (fixed or poisson)"),
+		thinkTime:    fs.Duration("think-time", 0, "mean pause of each closed-loop worker after every operation"),
+		thinkDist:    fs.String("think-dist", runner.ThinkFixed, "think time distribution (fixed, uniform or exponential)"),
+		pacing:       fs.Duration("pacing", 0, "start one iteration per interval on each closed-loop worker (0 = back to back)"),
+		profileName:  fs.String("profile", runner.ProfileConstant, "load profile (constant, ramp, step, spike, or a profile from config.yaml)"),
+		profileSteps: fs.Int("profile-steps", 5, "number of stages for the ramp and step profiles"),
+		interval:     fs.Duration("interval", time.Second, "width of the time-series windows in the result (0 = disabled)"),
+		retry:        fs.Int("retry-attempts", 3, "maximum attempts per operation, including the first (1 = no retries)"),
+		retryBackoff: fs.Duration("retry-backoff", 10*time.Millisecond, "backoff before the first retry; doubles on every further retry"),
+		retryMax:     fs.Duration("retry-max-backoff", time.Second, "upper bound on the retry backoff"),
+		retryOn:      &retryOn,
+		opTimeout:    fs.Duration("op-timeout", 0, "timeout for a single operation attempt (0 = none)"),
+		seed:         fs.Int64("seed", 0, "seed for all workload randomness (0 = pick one and report it)"),
+		metricsAddr:  fs.String("metrics-addr", "", "serve live Prometheus metrics at /metrics on this address while running (empty = don't)"),
+		quiet:        fs.Bool("quiet", false, "don't print progress to stderr"),
+		progressTick: fs.Duration("progress-interval", 5*time.Second, "interval between progress lines on stderr during a run (0 = none)"),
+	}
+}
+
+// options builds the runner options described by the flags.
+func (f *runFlags) options(cfg *config.Config) (runner.Options, error) {
+	profile, err := loadProfile(cfg, *f.profileName, *f.concurrency, *f.rate, *f.duration, *f.profileSteps)
+	if err != nil {
+		return runner.Options{}, fmt.Errorf("invalid load profile: %w", err)
+	}
+	seed := *f.seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return runner.Options{
+		Concurrency: *f.concurrency,
+		Duration:    *f.duration,
+		Warmup:      *f.warmup,
+		Cooldown:    *f.cooldown,
+		Rate:        *f.rate,
+		Arrival:     *f.arrival,
+		ThinkTime:   *f.thinkTime,
+		Think:       *f.thinkDist,
+		Pacing:      *f.pacing,
+		Profile:     profile,
+		Interval:    *f.interval,
+		OpTimeout:   *f.opTimeout,
+		Seed:        seed,
+		Retry: runner.RetryPolicy{
+			MaxAttempts: *f.retry,
+			Backoff:     *f.retryBackoff,
+			MaxBackoff:  *f.retryMax,
+			Classes:     *f.retryOn,
+		},
+	}, nil
+}
+
+// benchmark is a connected database and the test selected to run on it.
+type benchmark struct {
+	cfg          *config.Config
+	dbType       string
+	workloadName string
+	testName     string
+	driver       database.DatabaseDriver
+	workload     database.Workload
+	// progress prints to stderr, or nowhere with --quiet.
+	progress *progress.Reporter
+}
+
+// openBenchmark loads the config, connects to the database and looks up the
+// test named by the flags. The caller must close the driver.
+func openBenchmark(f *runFlags) (*benchmark, error) {
+	cfg, err := config.LoadConfig("config.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	dbs := map[string]database.DatabaseDriver{
+		"postgres": &database.PostgresDriver{},
+		"mysql":    &database.MySQLDriver{},
+		"mongo":    &database.MongoDriver{},
+	}
+
+	driver, ok := dbs[*f.dbType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database type: %s", *f.dbType)
+	}
+
+	workload, ok := workloads[*f.workloadName][*f.testName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported workload/test: %s/%s", *f.workloadName, *f.testName)
+	}
+
+	var dsn string
+	switch *f.dbType {
+	case "postgres":
+		dsn = cfg.Databases.Postgres
+	case "mysql":
+		dsn = cfg.Databases.MySQL
+	case "mongo":
+		dsn = cfg.Databases.Mongo
+	}
+	if err := driver.Connect(dsn); err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", *f.dbType, err)
+	}
+
+	return &benchmark{
+		cfg:          cfg,
+		dbType:       *f.dbType,
+		workloadName: *f.workloadName,
+		testName:     *f.testName,
+		driver:       driver,
+		workload:     workload,
+		progress:     f.progress(),
+	}, nil
+}
+
+// progress returns a reporter that prints to stderr, or nowhere with --quiet.
+func (f *runFlags) progress() *progress.Reporter {
+	if *f.quiet {
+		return progress.New(io.Discard, 0)
+	}
+	return progress.New(os.Stderr, *f.progressTick)
+}
+
+// monitor returns the monitor of b's runs asked for by the flags: the
+// progress lines unless --quiet, and the --metrics-addr endpoint. The
+// returned function stops serving metrics.
+func (f *runFlags) monitor(b *benchmark) (runner.Monitor, func(), error) {
+	var monitors []runner.Monitor
+	if !*f.quiet {
+		monitors = append(monitors, b.progress)
+	}
+	stop := func() {}
+	if *f.metricsAddr != "" {
+		collector, stopMetrics, err := serveMetrics(*f.metricsAddr, b)
+		if err != nil {
+			return nil, nil, err
+		}
+		monitors = append(monitors, collector)
+		stop = stopMetrics
+	}
+	return runner.Monitors(monitors...), stop, nil
+}
+
+// teardownTimeout bounds the teardown that still runs after an interrupt.
+const teardownTimeout = time.Minute
+
+// run resets the database, sets the test up, runs it and tears it down again.
+// Teardown also runs when setup fails or ctx is canceled, in which case the
+// returned result is partial and marked as interrupted.
+func (b *benchmark) run(ctx context.Context, opts runner.Options, logger *log.Logger) (result *database.Result, err error) {
+	// Reset the database to ensure a clean state before setup
+	b.progress.Printf("Resetting %s", b.dbType)
+	if err := b.driver.Reset(ctx); err != nil {
+		return nil, fmt.Errorf("failed to reset database: %w", err)
+	}
+
+	defer func() {
+		teardownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), teardownTimeout)
+		defer cancel()
+		b.progress.Printf("Tearing down %s/%s", b.workloadName, b.testName)
+		if err := b.workload.Teardown(teardownCtx, b.driver, logger); err != nil {
+			logger.Printf("Failed to teardown database: %v", err)
+		}
+	}()
+	if seeder, ok := b.workload.(database.Seeder); ok {
+		seeder.Seed(opts.Seed)
+	}
+	if reporter, ok := b.workload.(database.SeedReporter); ok {
+		reporter.SetSeedProgress(b.progress.Seeding)
+	}
+	b.progress.Printf("Setting up %s/%s", b.workloadName, b.testName)
+	if err := b.workload.Setup(ctx, b.driver, logger); err != nil {
+		return nil, fmt.Errorf("failed to setup database: %w", err)
+	}
+
+	logger.Printf("Running benchmark for %s/%s on %s...\n", b.workloadName, b.testName, b.dbType)
+	b.progress.Printf("Running %s/%s on %s", b.workloadName, b.testName, b.dbType)
+
+	result, err = runner.Run(ctx, b.driver, b.workload, opts, logger)
+	if err != nil {
+		return nil, fmt.Errorf("benchmark failed: %w", err)
+	}
+	return result, nil
+}
+
+func runCommand(ctx context.Context, args []string, logger *log.Logger) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	f := bindRunFlags(fs)
+	format := fs.String("output", output.Table, "result format ("+strings.Join(output.Formats, ", ")+")")
+	outputFile := fs.String("output-file", "", "write the result to this file instead of stdout")
+	resultsDir := fs.String("results", store.DefaultRoot, "directory to keep a record of every run in (empty = don't)")
+	repeat := fs.Int("repeat", 1, "number of trials to run, resetting the database between them")
+	junitFile := fs.String("junit-file", "", "write the SLO verdict to this file as JUnit XML")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := output.CheckFormat(*format); err != nil {
+		logger.Println(err)
+		return 2
+	}
+	if *repeat < 1 {
+		logger.Println("--repeat must be at least 1")
+		return 2
+	}
+
+	b, err := openBenchmark(f)
+	if err != nil {
+		logger.Println(err)
+		return 1
+	}
+	defer b.driver.Close()
+
+	opts, err := f.options(b.cfg)
+	if err != nil {
+		logger.Println(err)
+		return 1
+	}
+	monitor, stopMonitor, err := f.monitor(b)
+	if err != nil {
+		logger.Println(err)
+		return 1
+	}
+	defer stopMonitor()
+	opts.Monitor = monitor
+
+	serverVersion, err := b.driver.ServerVersion(ctx)
+	if err != nil {
+		logger.Printf("Failed to get database server version: %v", err)
+	}
+	startedAt := time.Now()
+	var trials []*database.Result
+	for i := 1; i <= *repeat; i++ {
+		if *repeat > 1 {
+			logger.Printf("Trial %d/%d", i, *repeat)
+			b.progress.Printf("Trial %d/%d", i, *repeat)
+		}
+		trial, err := b.run(ctx, opts, logger)
+		if err != nil {
+			logger.Println(err)
+			return 1
+		}
+		jsonOutput, err := json.MarshalIndent(trial, "", "  ")
+		if err != nil {
+			logger.Printf("Failed to marshal result: %v", err)
+			return 1
+		}
+		logger.Println(string(jsonOutput))
+		trials = append(trials, trial)
+		if trial.Interrupted {
+			break
+		}
+	}
+	result := runner.Merge(trials)
+
+	run := output.Run{DB: b.dbType, Workload: b.workloadName, Test: b.testName, Result: result}
+	if len(trials) > 1 {
+		run.Trials = trials
+		run.Summary = compare.Summarize(trials)
+	}
+	if *resultsDir != "" {
+		dir, err := b.save(*resultsDir, run, opts, startedAt, serverVersion, args, flagValues(fs))
+		if err != nil {
+			logger.Printf("Failed to save run: %v", err)
+			return 1
+		}
+		logger.Printf("Saved run to %s", dir)
+	}
+	if err := writeOutput(*outputFile, *format, run); err != nil {
+		logger.Printf("Failed to write result: %v", err)
+		return 1
+	}
+
+	var verdicts []slo.Verdict
+	if criteria, ok := b.cfg.SLO(b.workloadName, b.testName); ok {
+		verdict := slo.Evaluate(b.dbType, b.workloadName, b.testName, criteria, result)
+		printVerdict(os.Stderr, verdict)
+		verdicts = append(verdicts, verdict)
+	}
+	if *junitFile != "" {
+		if err := writeJUnit(*junitFile, verdicts); err != nil {
+			logger.Printf("Failed to write JUnit report: %v", err)
+			return 1
+		}
+	}
+
+	if result.Interrupted {
+		logger.Println("Benchmark interrupted; the result above is partial")
+		return exitInterrupted
+	}
+	for _, verdict := range verdicts {
+		if !verdict.Passed() {
+			logger.Printf("%s did not meet its SLO", verdict.Name())
+			return 1
+		}
+	}
+	return 0
+}
+
+// save records run in the store in root, with the command line and flag
+// values that produced it, and returns its directory.
+func (b *benchmark) save(root string, run output.Run, opts runner.Options, startedAt time.Time, serverVersion string, args []string, flags map[string]string) (string, error) {
+	meta := store.Metadata{
+		StartedAt:     startedAt,
+		DB:            b.dbType,
+		Workload:      b.workloadName,
+		Test:          b.testName,
+		Args:          args,
+		Flags:         flags,
+		Options:       opts,
+		Seed:          opts.Seed,
+		ToolCommit:    store.ToolCommit(),
+		GoVersion:     runtime.Version(),
+		Host:          store.CurrentHost(),
+		ServerVersion: serverVersion,
+	}
+	return store.Save(root, run, meta, b.cfg)
+}
+
+// flagValues returns the effective value of every flag in fs.
+func flagValues(fs *flag.FlagSet) map[string]string {
+	values := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		values[f.Name] = f.Value.String()
+	})
+	return values
+}
+
+// writeOutput renders runs in format to path, or to stdout when path is empty.
+func writeOutput(path, format string, runs ...output.Run) error {
+	if path == "" {
+		return output.Write(os.Stdout, format, runs...)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := output.Write(file, format, runs...); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// loadProfile resolves --profile into runner stages. Custom profiles from the
+// config take precedence; built-in profiles peak at the rate in open-loop
+// mode and at the concurrency otherwise.
+func loadProfile(cfg *config.Config, name string, concurrency int, rate float64, duration time.Duration, steps int) ([]runner.Stage, error) {
+	if custom, ok := cfg.Profiles[name]; ok {
+		stages := make([]runner.Stage, len(custom))
+		for i, stage := range custom {
+			stages[i] = runner.Stage{Duration: stage.Duration, Target: stage.Target, Ramp: stage.Ramp}
+		}
+		return stages, nil
+	}
+
+	target := float64(concurrency)
+	if rate > 0 {
+		target = rate
+	}
+	return runner.BuildProfile(name, target, duration, steps)
+}
+
+// errorClassList is a comma-separated flag value of database error classes.
+// Unknown classes are rejected when the flags are parsed.
+type errorClassList []string
+
+func (l *errorClassList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *errorClassList) Set(value string) error {
+	classes := splitList(value)
+	for _, class := range classes {
+		if !slices.Contains(database.KnownErrorClasses, class) {
+			return fmt.Errorf("unknown error class %q", class)
+		}
+	}
+	*l = classes
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"database-benchmark/internal/database"
+	"database-benchmark/internal/runner"
+)
+
+// searchCommand finds the maximum sustainable open-loop rate of a test by
+// probing it at increasing rates against a latency and error SLO.
+func searchCommand(ctx context.Context, args []string, logger *log.Logger) int {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	f := bindRunFlags(fs)
+	minRate := fs.Float64("min-rate", 10, "first rate to probe, in operations per second")
+	maxRate := fs.Float64("max-rate", 0, "highest rate to probe (0 = keep doubling until a probe fails)")
+	maxP99 := fs.Duration("max-p99", 50*time.Millisecond, "p99 latency a probe must stay under")
+	maxErrorRate := fs.Float64("max-error-rate", 0.001, "highest error rate a probe may have (0 allows no errors)")
+	precision := fs.Float64("precision", 0.05, "stop once the search window is within this fraction of the found rate")
+	maxProbes := fs.Int("max-probes", 20, "maximum number of probes")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	b, err := openBenchmark(f)
+	if err != nil {
+		logger.Println(err)
+		return 1
+	}
+	defer b.driver.Close()
+
+	opts, err := f.options(b.cfg)
+	if err != nil {
+		logger.Println(err)
+		return 1
+	}
+	if len(opts.Profile) > 0 {
+		logger.Println("search does not support load profiles")
+		return 1
+	}
+	monitor, stopMonitor, err := f.monitor(b)
+	if err != nil {
+		logger.Println(err)
+		return 1
+	}
+	defer stopMonitor()
+	opts.Monitor = monitor
+
+	searchOpts := runner.SearchOptions{
+		MinRate:      *minRate,
+		MaxRate:      *maxRate,
+		MaxP99:       *maxP99,
+		MaxErrorRate: *maxErrorRate,
+		Precision:    *precision,
+		MaxProbes:    *maxProbes,
+	}
+	search, err := runner.Search(ctx, searchOpts, func(ctx context.Context, rate float64) (*database.Result, error) {
+		opts.Rate = rate
+		logger.Printf("Probing %s/%s on %s at %.1f ops/s\n", b.workloadName, b.testName, b.dbType, rate)
+		b.progress.Printf("Probing at %.1f ops/s", rate)
+		return b.run(ctx, opts, logger)
+	})
+	if search != nil {
+		printSearch(b, searchOpts, search)
+		jsonOutput, err := json.MarshalIndent(search, "", "  ")
+		if err != nil {
+			logger.Printf("Failed to marshal search result: %v", err)
+			return 1
+		}
+		logger.Println(string(jsonOutput))
+	}
+	if ctx.Err() != nil {
+		logger.Println("Search interrupted; the probes above are the ones that completed")
+		return exitInterrupted
+	}
+	if err != nil {
+		logger.Printf("Search failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+func printSearch(b *benchmark, opts runner.SearchOptions, search *runner.SearchResult) {
+	fmt.Printf("Rate search for %s/%s on %s (p99 < %v, errors <= %.2f%%)\n", b.workloadName, b.testName, b.dbType, opts.MaxP99, opts.MaxErrorRate*100)
+	fmt.Printf("%-6s %12s %12s %12s %10s  %s\n", "probe", "rate", "throughput", "p99", "errors", "verdict")
+	for i, probe := range search.Probes {
+		verdict := "fail"
+		if probe.Passed {
+			verdict = "pass"
+		}
+		fmt.Printf("%-6d %12.1f %12.1f %12v %9.2f%%  %s\n", i+1, probe.Rate, probe.Result.Throughput, probe.Result.P99Latency, probe.Result.ErrorRate*100, verdict)
+	}
+	if search.Rate > 0 {
+		fmt.Printf("Maximum sustainable rate: %.1f ops/s\n", search.Rate)
+	} else {
+		fmt.Println("No probed rate met the SLO")
+	}
+}
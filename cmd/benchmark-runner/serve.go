@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"database-benchmark/internal/report"
+	"database-benchmark/internal/store"
+)
+
+// shutdownTimeout bounds how long the dashboard waits for open requests when
+// it is stopped.
+const shutdownTimeout = 5 * time.Second
+
+// serveCommand serves a dashboard over the run store until ctx is done.
+func serveCommand(ctx context.Context, args []string, logger *log.Logger) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	resultsDir := fs.String("results", store.DefaultRoot, "run store to serve")
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if _, err := os.Stat(*resultsDir); err != nil {
+		logger.Printf("Failed to open run store: %v", err)
+		return 2
+	}
+
+	server := &http.Server{Addr: *addr, Handler: report.Handler(*resultsDir)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("Serving %s on http://%s\n", *resultsDir, *addr)
+	logger.Printf("Serving %s on http://%s", *resultsDir, *addr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		logger.Printf("Failed to serve: %v", err)
+		return 1
+	}
+	return 0
+}
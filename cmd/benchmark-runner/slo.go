@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"database-benchmark/internal/slo"
+)
+
+// printVerdict writes the outcome of every SLO criterion of a run to w.
+func printVerdict(w io.Writer, v slo.Verdict) {
+	status := "PASS"
+	if !v.Passed() {
+		status = "FAIL"
+	}
+	fmt.Fprintf(w, "SLO for %s: %s\n", v.Name(), status)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, check := range v.Checks {
+		result := "pass"
+		if !check.Passed {
+			result = "FAIL"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", check.Name, check.Want, check.Got, result)
+	}
+	tw.Flush()
+}
+
+// writeJUnit writes verdicts to path as JUnit XML.
+func writeJUnit(path string, verdicts []slo.Verdict) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := slo.WriteJUnit(file, verdicts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"database-benchmark/internal/report"
+	"database-benchmark/internal/store"
+)
+
+// summaryCommand regenerates the results matrix of every database, workload
+// and test from the latest runs in the store.
+func summaryCommand(args []string, logger *log.Logger) int {
+	fs := flag.NewFlagSet("summary", flag.ContinueOnError)
+	resultsDir := fs.String("results", store.DefaultRoot, "run store to summarize")
+	outputFile := fs.String("output-file", "test_results.md", "file to write the summary to")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	entries, err := store.List(*resultsDir)
+	if err != nil {
+		logger.Printf("Failed to load runs: %v", err)
+		return 2
+	}
+
+	matrix := report.Matrix{DBs: databases, Tests: allTests()}
+
+	file, err := os.Create(*outputFile)
+	if err != nil {
+		logger.Printf("Failed to create summary: %v", err)
+		return 1
+	}
+	if err := report.WriteMarkdown(file, matrix, entries, filepath.Dir(*outputFile), time.Now()); err != nil {
+		file.Close()
+		logger.Printf("Failed to write summary: %v", err)
+		return 1
+	}
+	if err := file.Close(); err != nil {
+		logger.Printf("Failed to write summary: %v", err)
+		return 1
+	}
+	logger.Printf("Wrote summary of %d runs to %s", len(entries), *outputFile)
+	return 0
+}
+
+// allTests lists the tests of every workload, ordered by workload and test.
+func allTests() []report.Test {
+	var tests []report.Test
+	for workload, byName := range workloads {
+		for test := range byName {
+			tests = append(tests, report.Test{Workload: workload, Test: test})
+		}
+	}
+	sort.Slice(tests, func(i, j int) bool {
+		a, b := tests[i], tests[j]
+		if a.Workload != b.Workload {
+			return a.Workload < b.Workload
+		}
+		return a.Test < b.Test
+	})
+	return tests
+}
@@ -0,0 +1,226 @@
+// Package compare finds regressions between a baseline and a candidate
+// result.
+package compare
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"database-benchmark/internal/database"
+	"database-benchmark/internal/stats"
+)
+
+// Metrics compared between two results.
+const (
+	Throughput = "throughput"
+	P50        = "p50"
+	P90        = "p90"
+	P99        = "p99"
+	P999       = "p99.9"
+	P9999      = "p99.99"
+	Max        = "max"
+	ErrorRate  = "error_rate"
+	Integrity  = "integrity"
+)
+
+// Metrics lists every metric in the order they are reported.
+var Metrics = []string{Throughput, P50, P90, P99, P999, P9999, Max, ErrorRate, Integrity}
+
+// DefaultGate lists the metrics that fail a comparison by default. The tail
+// percentiles and max are reported but too noisy to gate on.
+var DefaultGate = []string{Throughput, P99, ErrorRate, Integrity}
+
+// Thresholds decides when a change is a regression.
+type Thresholds struct {
+	// MaxRegression is the fraction by which throughput may fall or a
+	// latency percentile may rise.
+	MaxRegression float64
+	// MaxErrorRateIncrease is the absolute amount by which the error rate
+	// may rise.
+	MaxErrorRateIncrease float64
+	// Gate lists the metrics whose regressions fail the comparison.
+	Gate []string
+}
+
+// Delta is the change of one metric from baseline to candidate.
+type Delta struct {
+	Metric    string
+	Baseline  string
+	Candidate string
+	// Change is the relative change for throughput and latencies, and the
+	// absolute change for the error rate.
+	Change string
+	// Regressed is set when the change is worse than the thresholds allow,
+	// and Gated when the metric is one that fails the comparison.
+	Regressed bool
+	Gated     bool
+}
+
+// Compare returns the change of every metric in Metrics.
+func Compare(baseline, candidate *database.Result, t Thresholds) []Delta {
+	gated := make(map[string]bool)
+	for _, metric := range t.Gate {
+		gated[metric] = true
+	}
+
+	var deltas []Delta
+	add := func(d Delta) {
+		d.Gated = gated[d.Metric]
+		deltas = append(deltas, d)
+	}
+
+	change := relative(baseline.Throughput, candidate.Throughput)
+	add(Delta{
+		Metric:    Throughput,
+		Baseline:  fmt.Sprintf("%.1f ops/s", baseline.Throughput),
+		Candidate: fmt.Sprintf("%.1f ops/s", candidate.Throughput),
+		Change:    formatRelative(change),
+		Regressed: -change > t.MaxRegression,
+	})
+
+	latencies := []struct {
+		metric              string
+		baseline, candidate time.Duration
+	}{
+		{P50, baseline.P50Latency, candidate.P50Latency},
+		{P90, baseline.P90Latency, candidate.P90Latency},
+		{P99, baseline.P99Latency, candidate.P99Latency},
+		{P999, baseline.P999Latency, candidate.P999Latency},
+		{P9999, baseline.P9999Latency, candidate.P9999Latency},
+		{Max, baseline.MaxLatency, candidate.MaxLatency},
+	}
+	for _, l := range latencies {
+		change := relative(float64(l.baseline), float64(l.candidate))
+		add(Delta{
+			Metric:    l.metric,
+			Baseline:  l.baseline.String(),
+			Candidate: l.candidate.String(),
+			Change:    formatRelative(change),
+			Regressed: change > t.MaxRegression,
+		})
+	}
+
+	add(Delta{
+		Metric:    ErrorRate,
+		Baseline:  fmt.Sprintf("%.3f%%", baseline.ErrorRate*100),
+		Candidate: fmt.Sprintf("%.3f%%", candidate.ErrorRate*100),
+		Change:    fmt.Sprintf("%+.3fpp", (candidate.ErrorRate-baseline.ErrorRate)*100),
+		Regressed: candidate.ErrorRate-baseline.ErrorRate > t.MaxErrorRateIncrease,
+	})
+
+	add(Delta{
+		Metric:    Integrity,
+		Baseline:  baseline.Integrity(),
+		Candidate: candidate.Integrity(),
+		Regressed: candidate.Verified && !candidate.DataIntegrity && !(baseline.Verified && !baseline.DataIntegrity),
+	})
+	return deltas
+}
+
+// Value returns the numeric value of metric in result: operations per second
+// for throughput, nanoseconds for latencies and a fraction for the error rate.
+// Integrity has no numeric value.
+func Value(metric string, result *database.Result) (float64, bool) {
+	switch metric {
+	case Throughput:
+		return result.Throughput, true
+	case P50:
+		return float64(result.P50Latency), true
+	case P90:
+		return float64(result.P90Latency), true
+	case P99:
+		return float64(result.P99Latency), true
+	case P999:
+		return float64(result.P999Latency), true
+	case P9999:
+		return float64(result.P9999Latency), true
+	case Max:
+		return float64(result.MaxLatency), true
+	case ErrorRate:
+		return result.ErrorRate, true
+	default:
+		return 0, false
+	}
+}
+
+// Summarize returns the summary of every numeric metric over repeated trials,
+// keyed by metric.
+func Summarize(trials []*database.Result) map[string]stats.Summary {
+	summary := make(map[string]stats.Summary)
+	for _, metric := range Metrics {
+		var samples []float64
+		for _, trial := range trials {
+			if v, ok := Value(metric, trial); ok {
+				samples = append(samples, v)
+			}
+		}
+		if len(samples) > 0 {
+			summary[metric] = stats.Summarize(samples)
+		}
+	}
+	return summary
+}
+
+// MinSamples is the number of intervals each side needs for Significance.
+const MinSamples = 8
+
+// Significance tests whether the throughput of baseline and candidate differs,
+// using the Mann-Whitney U test on their per-interval throughput. It returns
+// the p-value and the number of intervals on each side; ok is false when
+// either side has fewer than MinSamples intervals.
+func Significance(baseline, candidate *database.Result) (p float64, n1, n2 int, ok bool) {
+	a, b := intervalThroughput(baseline), intervalThroughput(candidate)
+	if len(a) < MinSamples || len(b) < MinSamples {
+		return 0, len(a), len(b), false
+	}
+	_, p = stats.MannWhitney(a, b)
+	return p, len(a), len(b), true
+}
+
+// intervalThroughput returns the throughput of every full-length interval of
+// result. The last interval of a run can be shorter and is left out.
+func intervalThroughput(result *database.Result) []float64 {
+	var width time.Duration
+	for _, interval := range result.Intervals {
+		if interval.Duration > width {
+			width = interval.Duration
+		}
+	}
+	var samples []float64
+	for _, interval := range result.Intervals {
+		if interval.Duration == width {
+			samples = append(samples, interval.Throughput)
+		}
+	}
+	return samples
+}
+
+// Failed reports whether any gated metric regressed.
+func Failed(deltas []Delta) bool {
+	for _, d := range deltas {
+		if d.Gated && d.Regressed {
+			return true
+		}
+	}
+	return false
+}
+
+// relative returns the change from baseline to candidate as a fraction of
+// baseline. A change from zero is infinite.
+func relative(baseline, candidate float64) float64 {
+	if baseline == 0 {
+		if candidate == 0 {
+			return 0
+		}
+		return math.Copysign(math.Inf(1), candidate)
+	}
+	return (candidate - baseline) / baseline
+}
+
+func formatRelative(change float64) string {
+	if math.IsInf(change, 0) {
+		return "new"
+	}
+	return fmt.Sprintf("%+.1f%%", change*100)
+}
@@ -0,0 +1,157 @@
+package compare
+
+import (
+	"testing"
+	"time"
+
+	"database-benchmark/internal/database"
+)
+
+func result(throughput float64, p99 time.Duration, errorRate float64, verified, integrity bool) *database.Result {
+	return &database.Result{
+		Stats: database.Stats{
+			Throughput:  throughput,
+			P50Latency:  p99 / 4,
+			P90Latency:  p99 / 2,
+			P99Latency:  p99,
+			P999Latency: p99,
+			MaxLatency:  p99,
+			ErrorRate:   errorRate,
+		},
+		Verified:      verified,
+		DataIntegrity: integrity,
+	}
+}
+
+func withP9999(r *database.Result, p9999 time.Duration) *database.Result {
+	r.P9999Latency = p9999
+	return r
+}
+
+func TestCompare(t *testing.T) {
+	thresholds := Thresholds{MaxRegression: 0.1, MaxErrorRateIncrease: 0.001, Gate: DefaultGate}
+	tests := []struct {
+		name          string
+		baseline      *database.Result
+		candidate     *database.Result
+		wantRegressed []string
+		wantChange    map[string]string
+		wantFailed    bool
+	}{
+		{
+			name:       "unchanged",
+			baseline:   result(1000, 10*time.Millisecond, 0, true, true),
+			candidate:  result(1000, 10*time.Millisecond, 0, true, true),
+			wantChange: map[string]string{Throughput: "+0.0%", P99: "+0.0%", ErrorRate: "+0.000pp"},
+		},
+		{
+			name:       "within thresholds",
+			baseline:   result(1000, 10*time.Millisecond, 0, true, true),
+			candidate:  result(950, 10500*time.Microsecond, 0.0005, true, true),
+			wantChange: map[string]string{Throughput: "-5.0%", P99: "+5.0%", ErrorRate: "+0.050pp"},
+		},
+		{
+			name:          "throughput fell",
+			baseline:      result(1000, 10*time.Millisecond, 0, true, true),
+			candidate:     result(800, 10*time.Millisecond, 0, true, true),
+			wantRegressed: []string{Throughput},
+			wantChange:    map[string]string{Throughput: "-20.0%"},
+			wantFailed:    true,
+		},
+		{
+			name:          "latency rose",
+			baseline:      result(1000, 10*time.Millisecond, 0, true, true),
+			candidate:     result(1000, 20*time.Millisecond, 0, true, true),
+			wantRegressed: []string{P50, P90, P99, P999, Max},
+			wantChange:    map[string]string{P99: "+100.0%"},
+			wantFailed:    true,
+		},
+		{
+			name:          "error rate rose",
+			baseline:      result(1000, 10*time.Millisecond, 0.001, true, true),
+			candidate:     result(1000, 10*time.Millisecond, 0.003, true, true),
+			wantRegressed: []string{ErrorRate},
+			wantChange:    map[string]string{ErrorRate: "+0.200pp"},
+			wantFailed:    true,
+		},
+		{
+			name:          "integrity lost",
+			baseline:      result(1000, 10*time.Millisecond, 0, true, true),
+			candidate:     result(1000, 10*time.Millisecond, 0, true, false),
+			wantRegressed: []string{Integrity},
+			wantFailed:    true,
+		},
+		{
+			name:      "integrity already failing",
+			baseline:  result(1000, 10*time.Millisecond, 0, true, false),
+			candidate: result(1000, 10*time.Millisecond, 0, true, false),
+		},
+		{
+			name:      "integrity not checked",
+			baseline:  result(1000, 10*time.Millisecond, 0, false, false),
+			candidate: result(1000, 10*time.Millisecond, 0, false, false),
+		},
+		{
+			name:       "throughput from zero",
+			baseline:   result(0, 0, 0, false, false),
+			candidate:  result(100, 0, 0, false, false),
+			wantChange: map[string]string{Throughput: "new", P99: "+0.0%"},
+		},
+		{
+			name:          "ungated regression does not fail",
+			baseline:      withP9999(result(1000, 10*time.Millisecond, 0, true, true), 10*time.Millisecond),
+			candidate:     withP9999(result(1000, 10*time.Millisecond, 0, true, true), 50*time.Millisecond),
+			wantRegressed: []string{P9999},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deltas := Compare(tt.baseline, tt.candidate, thresholds)
+			if len(deltas) != len(Metrics) {
+				t.Fatalf("got %d deltas, want one per metric (%d)", len(deltas), len(Metrics))
+			}
+			regressed := make(map[string]bool)
+			for _, metric := range tt.wantRegressed {
+				regressed[metric] = true
+			}
+			for i, d := range deltas {
+				if d.Metric != Metrics[i] {
+					t.Errorf("delta %d is %s, want %s", i, d.Metric, Metrics[i])
+				}
+				if d.Regressed != regressed[d.Metric] {
+					t.Errorf("%s regressed = %v, want %v", d.Metric, d.Regressed, regressed[d.Metric])
+				}
+				if want, ok := tt.wantChange[d.Metric]; ok && d.Change != want {
+					t.Errorf("%s change = %q, want %q", d.Metric, d.Change, want)
+				}
+			}
+			if got := Failed(deltas); got != tt.wantFailed {
+				t.Errorf("Failed() = %v, want %v", got, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestValue(t *testing.T) {
+	r := result(1000, 10*time.Millisecond, 0.01, true, true)
+	tests := []struct {
+		metric string
+		want   float64
+		wantOK bool
+	}{
+		{metric: Throughput, want: 1000, wantOK: true},
+		{metric: P50, want: float64(2500 * time.Microsecond), wantOK: true},
+		{metric: P99, want: float64(10 * time.Millisecond), wantOK: true},
+		{metric: ErrorRate, want: 0.01, wantOK: true},
+		{metric: Integrity, wantOK: false},
+		{metric: "unknown", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.metric, func(t *testing.T) {
+			got, ok := Value(tt.metric, r)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Value(%q) = %v, %v, want %v, %v", tt.metric, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
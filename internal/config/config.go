@@ -1,14 +1,26 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"net/url"
 	"os"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
 	Databases         Databases         `yaml:"databases"`
 	BenchmarkSettings BenchmarkSettings `yaml:"benchmark_settings"`
+	// Profiles are custom load profiles selectable with --profile.
+	Profiles map[string][]ProfileStage `yaml:"profiles"`
+	// SLOs are the acceptance criteria of tests, by workload and test.
+	SLOs map[string]map[string]SLO `yaml:"slos"`
+	// Matrix is the default selection of the matrix command.
+	Matrix Matrix `yaml:"matrix"`
 }
 
 type Databases struct {
@@ -22,6 +34,68 @@ type BenchmarkSettings struct {
 	DefaultConcurrency int    `yaml:"default_concurrency"`
 }
 
+// ProfileStage is one stage of a custom load profile. Target is the number of
+// workers, or operations per second when running with --rate. With ramp set
+// the level moves linearly from the previous stage's target.
+type ProfileStage struct {
+	Duration time.Duration `yaml:"duration"`
+	Target   float64       `yaml:"target"`
+	Ramp     bool          `yaml:"ramp"`
+}
+
+// SLO is the acceptance criteria a run of a test must meet. Criteria left
+// out are not checked.
+type SLO struct {
+	// MinThroughput is in operations per second.
+	MinThroughput float64       `yaml:"min_throughput"`
+	MaxP50        time.Duration `yaml:"max_p50"`
+	MaxP99        time.Duration `yaml:"max_p99"`
+	MaxP999       time.Duration `yaml:"max_p999"`
+	// MaxErrorRate is a fraction of all operations; a pointer so that zero
+	// can be required.
+	MaxErrorRate     *float64 `yaml:"max_error_rate"`
+	RequireIntegrity bool     `yaml:"require_integrity"`
+}
+
+// SLO returns the acceptance criteria of a test, if the config has any.
+func (c *Config) SLO(workload, test string) (SLO, bool) {
+	slo, ok := c.SLOs[workload][test]
+	return slo, ok
+}
+
+// Matrix selects the cells of the matrix command. An empty list selects
+// every database, workload or test, or the default concurrency.
+type Matrix struct {
+	Databases   []string `yaml:"databases"`
+	Workloads   []string `yaml:"workloads"`
+	Tests       []string `yaml:"tests"`
+	Concurrency []int    `yaml:"concurrency"`
+}
+
+// Redacted returns a copy of the config with the passwords in the database
+// DSNs masked, safe to store next to results.
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.Databases = Databases{
+		Postgres: redactDSN(c.Databases.Postgres),
+		MySQL:    redactDSN(c.Databases.MySQL),
+		Mongo:    redactDSN(c.Databases.Mongo),
+	}
+	return &redacted
+}
+
+// redactDSN masks the password in a URL or MySQL DSN.
+func redactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	if cfg, err := mysql.ParseDSN(dsn); err == nil && cfg.Passwd != "" {
+		cfg.Passwd = "xxxxx"
+		return cfg.FormatDSN()
+	}
+	return dsn
+}
+
 func LoadConfig(path string) (*Config, error) {
 	config := &Config{}
 
@@ -30,8 +104,12 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
-	err = yaml.Unmarshal(file, config)
-	if err != nil {
+	// Unknown keys are rejected, so that a misspelled setting such as an
+	// SLO criterion is reported rather than silently left unchecked.
+	dec := yaml.NewDecoder(bytes.NewReader(file))
+	dec.KnownFields(true)
+	err = dec.Decode(config)
+	if err != nil && !errors.Is(err, io.EOF) {
 		return nil, err
 	}
 
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    SLO
+		wantErr bool
+	}{
+		{
+			name: "criteria",
+			yaml: "slos:\n  ecommerce:\n    order_processing: { max_p99: 50ms, require_integrity: true }\n",
+			want: SLO{MaxP99: 50 * time.Millisecond, RequireIntegrity: true},
+		},
+		{name: "empty file", yaml: ""},
+		{
+			name:    "misspelled criterion",
+			yaml:    "slos:\n  ecommerce:\n    order_processing: { max_p99: 50ms, max_eror_rate: 0.01 }\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown section",
+			yaml:    "benchmark:\n  default_duration: 30s\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := LoadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, _ := cfg.SLO("ecommerce", "order_processing")
+			if got != tt.want {
+				t.Errorf("SLO = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRepoConfig(t *testing.T) {
+	if _, err := LoadConfig(filepath.Join("..", "..", "config.yaml")); err != nil {
+		t.Fatalf("config.yaml does not load: %v", err)
+	}
+}
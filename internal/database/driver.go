@@ -2,26 +2,205 @@ package database
 
 import (
 	"context"
+	"errors"
 	"log"
+	"math/rand"
 	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
 )
 
+// Workload is a single benchmark test. The runner owns the worker goroutines,
+// timing and counting; a workload only prepares its data and performs one
+// operation at a time.
 type Workload interface {
 	Setup(ctx context.Context, db DatabaseDriver, logger *log.Logger) error
-	Run(ctx context.Context, db DatabaseDriver, concurrency int, duration time.Duration, logger *log.Logger) (*Result, error)
+	// Operation performs one unit of work. It is called repeatedly from each
+	// worker goroutine until the run ends.
+	Operation(ctx context.Context, db DatabaseDriver, worker *Worker) error
 	Teardown(ctx context.Context, db DatabaseDriver, logger *log.Logger) error
 }
 
-type Result struct {
+// WorkerInitializer is implemented by workloads that need per-worker state.
+// InitWorker is called once for each worker before the run starts.
+type WorkerInitializer interface {
+	InitWorker(ctx context.Context, db DatabaseDriver, worker *Worker) error
+}
+
+// Verifier is implemented by workloads that can check data integrity once
+// all workers have stopped. The outcome is reported as Result.DataIntegrity.
+type Verifier interface {
+	Verify(ctx context.Context, db DatabaseDriver, logger *log.Logger) (bool, error)
+}
+
+// Seeder is implemented by workloads that generate data in Setup. Seed is
+// called with the run's seed before Setup, which draws from
+// NewRand(seed, SetupStream).
+type Seeder interface {
+	Seed(seed int64)
+}
+
+// SeedProgress receives how many of the total rows of table Setup has
+// inserted so far.
+type SeedProgress func(table string, done, total int)
+
+// Report passes the progress on, if there is anyone to receive it.
+func (p SeedProgress) Report(table string, done, total int) {
+	if p != nil {
+		p(table, done, total)
+	}
+}
+
+// SeedReporter is implemented by workloads whose Setup inserts enough rows to
+// take a while. SetSeedProgress is called before Setup with the function to
+// report its progress to.
+type SeedReporter interface {
+	SetSeedProgress(progress SeedProgress)
+}
+
+// ErrWorkloadDone is returned by Operation when the workload has run out of
+// work (e.g. the inventory is depleted). The runner then stops all workers.
+var ErrWorkloadDone = errors.New("workload done")
+
+// OperationObserver receives the outcome of the steps a workload times with
+// Worker.Track.
+type OperationObserver interface {
+	ObserveOperation(name string, latency time.Duration, err error)
+}
+
+// Worker is the per-goroutine state handed to Workload.Operation.
+type Worker struct {
+	ID        int
+	Iteration int64
+	Logger    *log.Logger
+	// Rand is the worker's own generator, seeded from the run's seed. Every
+	// random key or value a workload uses must come from it so that a run
+	// can be reproduced.
+	Rand *rand.Rand
+	// State holds whatever the workload stored in InitWorker.
+	State interface{}
+	// Op names the current operation in Result.ByOperation. The runner
+	// clears it before every call to Operation.
+	Op string
+	// Observer is set by the runner to collect Track timings.
+	Observer OperationObserver
+}
+
+// Track runs fn as a named step of the current operation and reports its
+// latency and error under that name in Result.ByOperation.
+func (w *Worker) Track(name string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	if w.Observer != nil {
+		w.Observer.ObserveOperation(name, time.Since(start), err)
+	}
+	return err
+}
+
+// Stats are the counts and latencies of a set of operations. Latencies come
+// from a histogram with microsecond resolution and three significant digits
+// and only cover operations that succeeded.
+type Stats struct {
 	Operations     int64
 	Errors         int64
 	Throughput     float64
+	MinLatency     time.Duration
+	P50Latency     time.Duration
+	P90Latency     time.Duration
 	P95Latency     time.Duration
 	P99Latency     time.Duration
+	P999Latency    time.Duration
+	P9999Latency   time.Duration
+	MaxLatency     time.Duration
 	AverageLatency time.Duration
+	StdDevLatency  time.Duration
 	ErrorRate      float64
-	TotalTime      time.Duration
-	DataIntegrity  bool
+}
+
+type Result struct {
+	Stats
+	// TotalTime is the length of the measured phase. WarmupTime and
+	// CooldownTime are the unmeasured phases around it.
+	TotalTime    time.Duration
+	WarmupTime   time.Duration
+	CooldownTime time.Duration
+	// DataIntegrity is the outcome of the workload's Verifier, if Verified
+	// says it has one. VerifyError is why the Verifier could not complete.
+	DataIntegrity bool
+	Verified      bool
+	VerifyError   string `json:",omitempty"`
+	// TargetRate is the requested open-loop rate in operations per second,
+	// or zero for a closed-loop run.
+	TargetRate float64
+	// Backlog counts open-loop operations that were due before the end of
+	// the run but never started because every worker was busy.
+	Backlog int64
+	// VirtualUsers is the number of closed-loop workers that ran measured
+	// operations, and UserRate the mean rate at which each of them did, in
+	// operations per second.
+	VirtualUsers int     `json:",omitempty"`
+	UserRate     float64 `json:",omitempty"`
+	// Stages breaks the measured phase down by load profile stage.
+	Stages []StageResult `json:",omitempty"`
+	// Intervals is the measured phase as a time series of fixed windows.
+	Intervals []IntervalResult `json:",omitempty"`
+	// ByOperation breaks the results down by the operation names set
+	// through Worker.Op and Worker.Track.
+	ByOperation map[string]Stats `json:",omitempty"`
+	// ErrorClasses breaks Errors down by ClassifyError class.
+	ErrorClasses map[string]*ErrorClass `json:",omitempty"`
+	// Retries counts failed attempts that the runner retried, broken down
+	// by error class in RetriesByClass. GaveUp counts operations that still
+	// failed after the last allowed attempt; they are included in Errors.
+	Retries        int64
+	RetriesByClass map[string]int64 `json:",omitempty"`
+	GaveUp         int64
+	// Seed reproduces the data and keys every worker used.
+	Seed int64
+	// Histograms are the latency histograms behind the stats, in
+	// microseconds and stamped with the phase they cover. They are tagged
+	// "total", "stage-N" or "op-NAME" and saved in the run store rather
+	// than in the JSON result.
+	Histograms []*hdrhistogram.Histogram `json:"-"`
+	// Interrupted marks a partial result from a run that was stopped by a
+	// signal before its planned end.
+	Interrupted bool
+}
+
+// Integrity describes the outcome of the workload's integrity check: "ok",
+// "FAILED", or "-" when it was not checked.
+func (r *Result) Integrity() string {
+	switch {
+	case !r.Verified:
+		return "-"
+	case r.DataIntegrity:
+		return "ok"
+	default:
+		return "FAILED"
+	}
+}
+
+// StageResult is the part of a Result measured during one load profile stage.
+type StageResult struct {
+	// Start is the offset of the stage from the start of the measured phase.
+	Start    time.Duration
+	Duration time.Duration
+	// Target is the load level the stage reaches: workers in closed-loop
+	// mode, operations per second in open-loop mode.
+	Target float64
+	Stats
+}
+
+// IntervalResult is the part of a Result measured during one fixed window.
+// Operations are attributed to the window in which they completed.
+type IntervalResult struct {
+	// Start is the offset of the window from the start of the measured phase.
+	Start    time.Duration
+	Duration time.Duration
+	Stats
+	// Histogram is the window's latency histogram in microseconds, in the
+	// compressed base64 encoding used by HdrHistogram logs.
+	Histogram []byte `json:"-"`
 }
 
 type Row interface {
@@ -32,12 +211,17 @@ type Rows interface {
 	Next() bool
 	Scan(dest ...interface{}) error
 	Close()
+	// Err reports the error that ended iteration or came up while closing.
+	// Some drivers only report a failed query here.
+	Err() error
 }
 
 type DatabaseDriver interface {
 	Connect(dsn string) error
 	Close() error
 	Reset(ctx context.Context) error
+	// ServerVersion reports the version of the database server.
+	ServerVersion(ctx context.Context) (string, error)
 	// Add specific methods needed by workloads, e.g.:
 	ExecuteTx(ctx context.Context, txFunc func(tx interface{}) error) error
 	ExecContext(ctx context.Context, query string, args ...interface{}) (interface{}, error)
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Error classes reported in Result.ErrorClasses.
+const (
+	ErrorSerialization = "serialization_conflict"
+	ErrorDeadlock      = "deadlock"
+	ErrorLockTimeout   = "lock_timeout"
+	ErrorConnection    = "connection_failure"
+	ErrorConstraint    = "constraint_violation"
+	ErrorTimeout       = "context_timeout"
+	ErrorOther         = "other"
+)
+
+// KnownErrorClasses lists every class ClassifyError returns.
+var KnownErrorClasses = []string{
+	ErrorSerialization,
+	ErrorDeadlock,
+	ErrorLockTimeout,
+	ErrorConnection,
+	ErrorConstraint,
+	ErrorTimeout,
+	ErrorOther,
+}
+
+// MaxErrorSamples is the number of distinct messages kept per error class.
+const MaxErrorSamples = 5
+
+// ErrorClass counts the errors of one class and keeps a few distinct sample
+// messages.
+type ErrorClass struct {
+	Count   int64
+	Samples []string
+}
+
+// Add counts err and keeps its message if it is new and there is room.
+func (c *ErrorClass) Add(err error) {
+	c.Count++
+	if len(c.Samples) >= MaxErrorSamples {
+		return
+	}
+	msg := err.Error()
+	for _, sample := range c.Samples {
+		if sample == msg {
+			return
+		}
+	}
+	c.Samples = append(c.Samples, msg)
+}
+
+// Merge adds the count and samples of other, such as the same class in
+// another trial.
+func (c *ErrorClass) Merge(other *ErrorClass) {
+	c.Count += other.Count
+	for _, sample := range other.Samples {
+		if len(c.Samples) >= MaxErrorSamples {
+			return
+		}
+		if !slices.Contains(c.Samples, sample) {
+			c.Samples = append(c.Samples, sample)
+		}
+	}
+}
+
+// ClassifyError maps an error from any of the drivers to one of the error
+// classes above.
+func ClassifyError(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return classifySQLState(pgErr.Code)
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1213: // ER_LOCK_DEADLOCK
+			return ErrorDeadlock
+		case 1205, 3572: // ER_LOCK_WAIT_TIMEOUT, ER_LOCK_NOWAIT
+			return ErrorLockTimeout
+		case 1062, 1451, 1452, 1048, 1586, 3819: // duplicate key, foreign key, not null, check
+			return ErrorConstraint
+		case 3024: // ER_QUERY_TIMEOUT
+			return ErrorTimeout
+		case 1040, 1053, 1152, 1158, 1159, 1160, 1161: // too many connections, shutdown, aborted connection, network errors
+			return ErrorConnection
+		}
+		return classifySQLState(string(mysqlErr.SQLState[:]))
+	}
+
+	if class, ok := classifyMongoError(err); ok {
+		return class
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err):
+		return ErrorTimeout
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, mysql.ErrInvalidConn),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ErrorConnection
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorTimeout
+		}
+		return ErrorConnection
+	}
+	// The MySQL driver only logs the cause of a broken connection and
+	// returns this text.
+	if strings.Contains(err.Error(), "bad connection") {
+		return ErrorConnection
+	}
+	return ErrorOther
+}
+
+// classifySQLState maps a SQLSTATE code, as reported by PostgreSQL and MySQL.
+func classifySQLState(code string) string {
+	switch {
+	case code == "40001":
+		return ErrorSerialization
+	case code == "40P01":
+		return ErrorDeadlock
+	case code == "55P03":
+		return ErrorLockTimeout
+	case code == "57014":
+		return ErrorTimeout
+	case strings.HasPrefix(code, "08"), code == "57P01", code == "57P02", code == "57P03", code == "53300":
+		return ErrorConnection
+	case strings.HasPrefix(code, "23"):
+		return ErrorConstraint
+	}
+	return ErrorOther
+}
+
+func classifyMongoError(err error) (string, bool) {
+	// The commit may have applied, so running the operation again is not
+	// safe. MongoDriver.ExecuteTx retries the commit alone instead.
+	if hasErrorLabel(err, "UnknownTransactionCommitResult") {
+		return ErrorOther, true
+	}
+	if mongo.IsTimeout(err) {
+		return ErrorTimeout, true
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrorConstraint, true
+	}
+	if mongo.IsNetworkError(err) {
+		return ErrorConnection, true
+	}
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return "", false
+	}
+	switch {
+	case serverErr.HasErrorCode(112): // WriteConflict
+		return ErrorSerialization, true
+	case serverErr.HasErrorCode(24): // LockTimeout
+		return ErrorLockTimeout, true
+	case serverErr.HasErrorCode(121): // DocumentValidationFailure
+		return ErrorConstraint, true
+	case serverErr.HasErrorLabel("TransientTransactionError"):
+		return ErrorSerialization, true
+	}
+	return ErrorOther, true
+}
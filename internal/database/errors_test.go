@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "postgres serialization failure", err: &pgconn.PgError{Code: "40001"}, want: ErrorSerialization},
+		{name: "postgres deadlock", err: &pgconn.PgError{Code: "40P01"}, want: ErrorDeadlock},
+		{name: "postgres lock not available", err: &pgconn.PgError{Code: "55P03"}, want: ErrorLockTimeout},
+		{name: "postgres query canceled", err: &pgconn.PgError{Code: "57014"}, want: ErrorTimeout},
+		{name: "postgres connection exception", err: &pgconn.PgError{Code: "08006"}, want: ErrorConnection},
+		{name: "postgres too many connections", err: &pgconn.PgError{Code: "53300"}, want: ErrorConnection},
+		{name: "postgres unique violation", err: &pgconn.PgError{Code: "23505"}, want: ErrorConstraint},
+		{name: "postgres syntax error", err: &pgconn.PgError{Code: "42601"}, want: ErrorOther},
+		{name: "wrapped postgres error", err: fmt.Errorf("insert order: %w", &pgconn.PgError{Code: "40001"}), want: ErrorSerialization},
+
+		{name: "mysql deadlock", err: &mysql.MySQLError{Number: 1213}, want: ErrorDeadlock},
+		{name: "mysql lock wait timeout", err: &mysql.MySQLError{Number: 1205}, want: ErrorLockTimeout},
+		{name: "mysql duplicate key", err: &mysql.MySQLError{Number: 1062}, want: ErrorConstraint},
+		{name: "mysql query timeout", err: &mysql.MySQLError{Number: 3024}, want: ErrorTimeout},
+		{name: "mysql too many connections", err: &mysql.MySQLError{Number: 1040}, want: ErrorConnection},
+		{name: "mysql falls back to sqlstate", err: &mysql.MySQLError{Number: 9999, SQLState: [5]byte{'4', '0', '0', '0', '1'}}, want: ErrorSerialization},
+		{name: "mysql unknown error", err: &mysql.MySQLError{Number: 1064}, want: ErrorOther},
+
+		{name: "mongo write conflict", err: mongo.CommandError{Code: 112}, want: ErrorSerialization},
+		{name: "mongo lock timeout", err: mongo.CommandError{Code: 24}, want: ErrorLockTimeout},
+		{name: "mongo validation failure", err: mongo.CommandError{Code: 121}, want: ErrorConstraint},
+		{name: "mongo duplicate key", err: mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, want: ErrorConstraint},
+		{name: "mongo transient transaction error", err: mongo.CommandError{Code: 251, Labels: []string{"TransientTransactionError"}}, want: ErrorSerialization},
+		{name: "mongo unknown commit result", err: mongo.CommandError{Labels: []string{"UnknownTransactionCommitResult"}}, want: ErrorOther},
+		{name: "mongo commit lost to the network", err: mongo.CommandError{Labels: []string{"NetworkError", "UnknownTransactionCommitResult"}}, want: ErrorOther},
+		{name: "mongo other server error", err: mongo.CommandError{Code: 2}, want: ErrorOther},
+
+		{name: "context deadline", err: context.DeadlineExceeded, want: ErrorTimeout},
+		{name: "wrapped context deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded), want: ErrorTimeout},
+		{name: "bad connection", err: driver.ErrBadConn, want: ErrorConnection},
+		{name: "mysql invalid connection", err: mysql.ErrInvalidConn, want: ErrorConnection},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, want: ErrorConnection},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, want: ErrorConnection},
+		{name: "network timeout", err: &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, want: ErrorTimeout},
+		{name: "bad connection text", err: errors.New("driver: bad connection"), want: ErrorConnection},
+		{name: "anything else", err: errors.New("inventory depleted"), want: ErrorOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorClassAdd(t *testing.T) {
+	tests := []struct {
+		name        string
+		errs        []string
+		wantCount   int64
+		wantSamples []string
+	}{
+		{name: "one error", errs: []string{"a"}, wantCount: 1, wantSamples: []string{"a"}},
+		{name: "repeated message kept once", errs: []string{"a", "b", "a"}, wantCount: 3, wantSamples: []string{"a", "b"}},
+		{
+			name:        "samples capped",
+			errs:        []string{"a", "b", "c", "d", "e", "f", "g"},
+			wantCount:   7,
+			wantSamples: []string{"a", "b", "c", "d", "e"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c ErrorClass
+			for _, msg := range tt.errs {
+				c.Add(errors.New(msg))
+			}
+			if c.Count != tt.wantCount {
+				t.Errorf("Count = %d, want %d", c.Count, tt.wantCount)
+			}
+			if !reflect.DeepEqual(c.Samples, tt.wantSamples) {
+				t.Errorf("Samples = %q, want %q", c.Samples, tt.wantSamples)
+			}
+		})
+	}
+}
+
+func TestErrorClassMerge(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        ErrorClass
+		wantCount   int64
+		wantSamples []string
+	}{
+		{
+			name:        "distinct samples",
+			a:           ErrorClass{Count: 2, Samples: []string{"a"}},
+			b:           ErrorClass{Count: 3, Samples: []string{"b"}},
+			wantCount:   5,
+			wantSamples: []string{"a", "b"},
+		},
+		{
+			name:        "shared samples",
+			a:           ErrorClass{Count: 1, Samples: []string{"a"}},
+			b:           ErrorClass{Count: 1, Samples: []string{"a"}},
+			wantCount:   2,
+			wantSamples: []string{"a"},
+		},
+		{
+			name:        "samples capped",
+			a:           ErrorClass{Count: 4, Samples: []string{"a", "b", "c", "d"}},
+			b:           ErrorClass{Count: 2, Samples: []string{"e", "f"}},
+			wantCount:   6,
+			wantSamples: []string{"a", "b", "c", "d", "e"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.a.Merge(&tt.b)
+			if tt.a.Count != tt.wantCount {
+				t.Errorf("Count = %d, want %d", tt.a.Count, tt.wantCount)
+			}
+			if !reflect.DeepEqual(tt.a.Samples, tt.wantSamples) {
+				t.Errorf("Samples = %q, want %q", tt.a.Samples, tt.wantSamples)
+			}
+		})
+	}
+}
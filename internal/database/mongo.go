@@ -2,6 +2,8 @@ package database
 
 import (
 	"context"
+	"errors"
+	"sync/atomic"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -12,6 +14,8 @@ import (
 type MongoDriver struct {
 	client *mongo.Client
 	dsn    string
+	// standalone is set once the server has turned down a transaction.
+	standalone atomic.Bool
 }
 
 type MongoRow struct {
@@ -22,20 +26,27 @@ func (mr *MongoRow) Scan(dest ...interface{}) error {
 	return mr.singleResult.Decode(dest[0])
 }
 
+// MongoRows iterates a cursor under the context of the query that opened it.
 type MongoRows struct {
+	ctx    context.Context
 	cursor *mongo.Cursor
 }
 
 func (mr *MongoRows) Next() bool {
-	return mr.cursor.Next(context.Background())
+	return mr.cursor.Next(mr.ctx)
 }
 
 func (mr *MongoRows) Scan(dest ...interface{}) error {
 	return mr.cursor.Decode(dest[0])
 }
 
+func (mr *MongoRows) Err() error {
+	return mr.cursor.Err()
+}
+
 func (mr *MongoRows) Close() {
-	mr.cursor.Close(context.Background())
+	// The server-side cursor is released even when the query timed out.
+	mr.cursor.Close(context.WithoutCancel(mr.ctx))
 }
 
 func (md *MongoDriver) Connect(dsn string) error {
@@ -48,6 +59,14 @@ func (md *MongoDriver) Connect(dsn string) error {
 	return nil
 }
 
+func (md *MongoDriver) ServerVersion(ctx context.Context) (string, error) {
+	var info struct {
+		Version string `bson:"version"`
+	}
+	err := md.client.Database("admin").RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&info)
+	return info.Version, err
+}
+
 func (md *MongoDriver) Reset(ctx context.Context) error {
 	return md.client.Database("benchmarkdb").Drop(ctx)
 }
@@ -56,31 +75,66 @@ func (md *MongoDriver) Close() error {
 	return md.client.Disconnect(context.Background())
 }
 
+// maxCommitAttempts bounds how often ExecuteTx commits a transaction whose
+// outcome the server could not report.
+const maxCommitAttempts = 3
+
+// ExecuteTx runs txFunc in a transaction and commits it once. Unlike
+// WithTransaction it does not retry on transient errors itself, so failed
+// transactions are left to the runner's retry policy. Only a commit with an
+// unknown result is retried here, since running txFunc again could apply
+// its writes twice.
 func (md *MongoDriver) ExecuteTx(ctx context.Context, txFunc func(interface{}) error) error {
+	if md.standalone.Load() {
+		return txFunc(ctx)
+	}
 	session, err := md.client.StartSession()
 	if err != nil {
 		return err
 	}
 	defer session.EndSession(ctx)
 
-	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
-		if err := txFunc(sessCtx); err != nil {
-			return nil, err
+	if err := session.StartTransaction(); err != nil {
+		return err
+	}
+	sessCtx := mongo.NewSessionContext(ctx, session)
+	err = txFunc(sessCtx)
+	if err == nil {
+		err = session.CommitTransaction(sessCtx)
+		for attempt := 1; attempt < maxCommitAttempts && hasErrorLabel(err, "UnknownTransactionCommitResult") && ctx.Err() == nil; attempt++ {
+			err = session.CommitTransaction(sessCtx)
 		}
-		return nil, nil
-	})
+	} else {
+		_ = session.AbortTransaction(context.WithoutCancel(ctx))
+	}
 
 	// If the error is due to transactions not being supported (e.g., standalone server),
 	// execute the function directly without a transaction.
 	if err != nil && strings.Contains(err.Error(), "Transaction numbers are only allowed on a replica set member or mongos") {
+		md.standalone.Store(true)
 		return txFunc(ctx)
 	}
 
 	return err
 }
 
+func hasErrorLabel(err error, label string) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorLabel(label)
+}
+
+// sessionContext binds ctx to the session of the transaction a workload
+// stored in it under "tx", so that the operation runs in that transaction.
+func sessionContext(ctx context.Context) context.Context {
+	if sess, ok := ctx.Value("tx").(mongo.SessionContext); ok {
+		return mongo.NewSessionContext(ctx, sess)
+	}
+	return ctx
+}
+
 func (md *MongoDriver) ExecContext(ctx context.Context, query string, args ...interface{}) (interface{}, error) {
 	collection := md.client.Database("benchmarkdb").Collection(query)
+	ctx = sessionContext(ctx)
 
 	if len(args) == 1 {
 		return collection.InsertOne(ctx, args[0])
@@ -95,6 +149,7 @@ func (md *MongoDriver) ExecContext(ctx context.Context, query string, args ...in
 
 func (md *MongoDriver) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
 	collection := md.client.Database("benchmarkdb").Collection(query)
+	ctx = sessionContext(ctx)
 	var cursor *mongo.Cursor
 	var err error
 	if pipeline, ok := args[0].([]bson.M); ok {
@@ -105,12 +160,12 @@ func (md *MongoDriver) QueryContext(ctx context.Context, query string, args ...i
 	if err != nil {
 		return nil, err
 	}
-	return &MongoRows{cursor: cursor}, nil
+	return &MongoRows{ctx: ctx, cursor: cursor}, nil
 }
 
 func (md *MongoDriver) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
 	collection := md.client.Database("benchmarkdb").Collection(query)
 	var singleResult *mongo.SingleResult
-	singleResult = collection.FindOne(ctx, args[0])
+	singleResult = collection.FindOne(sessionContext(ctx), args[0])
 	return &MongoRow{singleResult: singleResult}
 }
@@ -35,6 +35,12 @@ func (md *MySQLDriver) Close() error {
 	return md.db.Close()
 }
 
+func (md *MySQLDriver) ServerVersion(ctx context.Context) (string, error) {
+	var version string
+	err := md.db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version)
+	return version, err
+}
+
 func (md *MySQLDriver) Reset(ctx context.Context) error {
 	rows, err := md.db.QueryContext(ctx, "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE()")
 	if err != nil {
@@ -91,28 +97,48 @@ func (md *MySQLDriver) ExecuteTx(ctx context.Context, txFunc func(tx interface{}
 
 func (md *MySQLDriver) ExecContext(ctx context.Context, query string, args ...interface{}) (interface{}, error) {
 	query = replacePlaceholders(query)
+	if tx, ok := ctx.Value("tx").(*sql.Tx); ok {
+		return tx.ExecContext(ctx, query, args...)
+	}
 	return md.db.ExecContext(ctx, query, args...)
 }
 
 type MySQLRows struct {
 	*sql.Rows
+	closeErr error
 }
 
 func (r *MySQLRows) Close() {
-	r.Rows.Close()
+	r.closeErr = r.Rows.Close()
+}
+
+func (r *MySQLRows) Err() error {
+	if err := r.Rows.Err(); err != nil {
+		return err
+	}
+	return r.closeErr
 }
 
 func (md *MySQLDriver) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
 	query = replacePlaceholders(query)
-	rows, err := md.db.QueryContext(ctx, query, args...)
+	var rows *sql.Rows
+	var err error
+	if tx, ok := ctx.Value("tx").(*sql.Tx); ok {
+		rows, err = tx.QueryContext(ctx, query, args...)
+	} else {
+		rows, err = md.db.QueryContext(ctx, query, args...)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &MySQLRows{rows}, nil
+	return &MySQLRows{Rows: rows}, nil
 }
 
 func (md *MySQLDriver) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
 	query = replacePlaceholders(query)
+	if tx, ok := ctx.Value("tx").(*sql.Tx); ok {
+		return tx.QueryRowContext(ctx, query, args...)
+	}
 	return md.db.QueryRowContext(ctx, query, args...)
 }
 
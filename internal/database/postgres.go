@@ -31,13 +31,16 @@ func (pd *PostgresDriver) Close() error {
 	return nil
 }
 
-func (pd *PostgresDriver) Reset(ctx context.Context) error {
-	fmt.Println("Resetting PostgreSQL database...")
+func (pd *PostgresDriver) ServerVersion(ctx context.Context) (string, error) {
+	var version string
+	err := pd.pool.QueryRow(ctx, "SELECT version()").Scan(&version)
+	return version, err
+}
 
+func (pd *PostgresDriver) Reset(ctx context.Context) error {
 	tablesToDrop := []string{"order_items", "payments", "orders", "products", "users", "posts", "follows", "timelines", "events"}
 
 	for _, tableName := range tablesToDrop {
-		fmt.Printf("Dropping table: %s\n", tableName)
 		_, err := pd.pool.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE", tableName))
 		if err != nil {
 			return err
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"math/rand"
+	randv2 "math/rand/v2"
+
+	"github.com/google/uuid"
+)
+
+// SetupStream is the NewRand stream for the data a workload generates in
+// Setup. Workers use their ID as the stream.
+const SetupStream = -1
+
+// NewRand returns the generator for one stream of a run's randomness. Each
+// stream is independent of how the others are consumed, so the same seed
+// reproduces every worker's data and keys regardless of scheduling. The
+// generators are small enough to give one to each of thousands of workers.
+func NewRand(seed int64, stream int) *rand.Rand {
+	return rand.New(&pcgSource{randv2.NewPCG(uint64(seed), uint64(stream))})
+}
+
+// pcgSource adapts a PCG generator to the math/rand Source interface.
+type pcgSource struct {
+	pcg *randv2.PCG
+}
+
+func (s *pcgSource) Int63() int64 {
+	return int64(s.pcg.Uint64() >> 1)
+}
+
+func (s *pcgSource) Uint64() uint64 {
+	return s.pcg.Uint64()
+}
+
+func (s *pcgSource) Seed(seed int64) {
+	s.pcg.Seed(uint64(seed), 0)
+}
+
+// NewUUID returns a version 4 UUID drawn from r instead of the system's
+// random source.
+func NewUUID(r *rand.Rand) string {
+	return uuid.Must(uuid.NewRandomFromReader(r)).String()
+}
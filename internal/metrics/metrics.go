@@ -0,0 +1,221 @@
+// Package metrics exposes a running benchmark in the Prometheus text format,
+// so that client-side numbers can be graphed next to database exporters.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"database-benchmark/internal/database"
+	"database-benchmark/internal/runner"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// The latency quantiles are computed over the last windows windows of
+// window each, in microseconds like the runner's histograms.
+const (
+	window  = time.Second
+	windows = 10
+)
+
+// quantiles are the latency quantiles exported.
+var quantiles = []float64{0.5, 0.9, 0.99, 0.999}
+
+// Collector follows a run as a runner.Monitor and serves what it saw as
+// Prometheus metrics. Counters keep growing across runs, such as the trials of
+// a repeated run or the probes of a search.
+type Collector struct {
+	labels string
+
+	mu           sync.Mutex
+	measureStart time.Time
+	measureEnd   time.Time
+	// operations counts the completed operations by phase and outcome.
+	operations map[[2]string]int64
+	errors     map[string]int64
+	retries    map[string]int64
+	inFlight   int64
+	// latency holds the recent latencies of successful operations, rotated
+	// every window up to windowEnd; latencySum and latencyCount cover the
+	// whole lifetime.
+	latency      *hdrhistogram.WindowedHistogram
+	windowEnd    time.Time
+	latencySum   time.Duration
+	latencyCount int64
+}
+
+// NewCollector returns a collector whose metrics carry the given constant
+// labels, such as the database and test.
+func NewCollector(labels map[string]string) *Collector {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = label(name, labels[name])
+	}
+	return &Collector{
+		labels:     strings.Join(pairs, ","),
+		operations: make(map[[2]string]int64),
+		errors:     make(map[string]int64),
+		retries:    make(map[string]int64),
+		latency:    hdrhistogram.NewWindowed(windows, 1, runner.MaxLatency, 3),
+		windowEnd:  time.Now().Add(window),
+	}
+}
+
+// Start notes the phases of a new run.
+func (c *Collector) Start(measureStart, measureEnd, deadline time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.measureStart = measureStart
+	c.measureEnd = measureEnd
+}
+
+// Begin counts an operation in flight.
+func (c *Collector) Begin() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight++
+}
+
+// Finish counts a completed operation and records its latency.
+func (c *Collector) Finish(latency time.Duration, err error) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+		c.errors[database.ClassifyError(err)]++
+	}
+	c.operations[[2]string{runner.Phase(now, c.measureStart, c.measureEnd), outcome}]++
+	if err != nil {
+		return
+	}
+	c.rotate(now)
+	c.latency.Current.RecordValue(runner.LatencyMicros(latency))
+	c.latencySum += latency
+	c.latencyCount++
+}
+
+// Abandon forgets an operation cut short by the end of the run.
+func (c *Collector) Abandon() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inFlight--
+}
+
+// Retry counts a retried attempt.
+func (c *Collector) Retry(class string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retries[class]++
+}
+
+// End is a no-op: the metrics stay up until the collector is no longer
+// served.
+func (c *Collector) End() {}
+
+// rotate moves the latency window forward to now.
+func (c *Collector) rotate(now time.Time) {
+	for i := 0; i < windows && !now.Before(c.windowEnd); i++ {
+		c.latency.Rotate()
+		c.windowEnd = c.windowEnd.Add(window)
+	}
+	if !now.Before(c.windowEnd) {
+		// Idle for longer than all windows: they are all empty now.
+		c.windowEnd = now.Add(window)
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Write(w)
+}
+
+// Write writes the metrics in the Prometheus text exposition format.
+func (c *Collector) Write(w io.Writer) error {
+	c.mu.Lock()
+	c.rotate(time.Now())
+	recent := c.latency.Merge()
+	var b strings.Builder
+
+	header(&b, "benchmark_operations_total", "counter", "Operations completed, by run phase and outcome.")
+	keys := make([][2]string, 0, len(c.operations))
+	for key := range c.operations {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1]
+	})
+	for _, key := range keys {
+		c.sample(&b, "benchmark_operations_total", label("phase", key[0])+","+label("outcome", key[1]), float64(c.operations[key]))
+	}
+
+	header(&b, "benchmark_errors_total", "counter", "Failed operations, by error class.")
+	c.byClass(&b, "benchmark_errors_total", c.errors)
+	header(&b, "benchmark_retries_total", "counter", "Failed attempts that were retried, by error class.")
+	c.byClass(&b, "benchmark_retries_total", c.retries)
+
+	header(&b, "benchmark_active_workers", "gauge", "Workers running an operation, including its retries.")
+	c.sample(&b, "benchmark_active_workers", "", float64(c.inFlight))
+
+	header(&b, "benchmark_operation_latency_seconds", "summary",
+		fmt.Sprintf("Latency of successful operations; quantiles over the last %v.", window*windows))
+	for _, q := range quantiles {
+		v := float64(recent.ValueAtQuantile(q*100)) * float64(time.Microsecond) / float64(time.Second)
+		c.sample(&b, "benchmark_operation_latency_seconds", label("quantile", fmt.Sprint(q)), v)
+	}
+	c.sample(&b, "benchmark_operation_latency_seconds_sum", "", c.latencySum.Seconds())
+	c.sample(&b, "benchmark_operation_latency_seconds_count", "", float64(c.latencyCount))
+	c.mu.Unlock()
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func header(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes one sample of name with the collector's labels and extra.
+func (c *Collector) sample(b *strings.Builder, name, extra string, value float64) {
+	labels := c.labels
+	if labels != "" && extra != "" {
+		labels += ","
+	}
+	labels += extra
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(b, "%s%s %v\n", name, labels, value)
+}
+
+func (c *Collector) byClass(b *strings.Builder, name string, counts map[string]int64) {
+	classes := make([]string, 0, len(counts))
+	for class := range counts {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		c.sample(b, name, label("class", class), float64(counts[class]))
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// label formats a label pair, escaping the value as the format requires.
+func label(name, value string) string {
+	return name + `="` + labelEscaper.Replace(value) + `"`
+}
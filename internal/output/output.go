@@ -0,0 +1,304 @@
+// Package output renders benchmark results for people and tools.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"database-benchmark/internal/compare"
+	"database-benchmark/internal/database"
+	"database-benchmark/internal/stats"
+)
+
+// Output formats.
+const (
+	Table     = "table"
+	JSON      = "json"
+	CSV       = "csv"
+	Markdown  = "markdown"
+	Benchstat = "benchstat"
+)
+
+// Formats lists every supported format.
+var Formats = []string{Table, JSON, CSV, Markdown, Benchstat}
+
+// Run is a result together with the test that produced it. Its JSON form is
+// the result with the test's coordinates added at the top level.
+type Run struct {
+	DB       string
+	Workload string
+	Test     string
+	*database.Result
+	// Trials holds every trial of a repeated run, whose pooled result is
+	// Result, and Summary the spread of each metric over the trials, keyed
+	// by compare metric name.
+	Trials  []*database.Result       `json:",omitempty"`
+	Summary map[string]stats.Summary `json:",omitempty"`
+}
+
+// Name identifies the run as db/workload/test.
+func (r Run) Name() string {
+	return r.DB + "/" + r.Workload + "/" + r.Test
+}
+
+// CheckFormat reports whether format is supported, so that callers can reject
+// it before running anything.
+func CheckFormat(format string) error {
+	for _, f := range Formats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported output format: %s (want one of %s)", format, strings.Join(Formats, ", "))
+}
+
+// Write renders runs to w in format.
+func Write(w io.Writer, format string, runs ...Run) error {
+	switch format {
+	case Table:
+		return writeTable(w, runs)
+	case JSON:
+		return writeJSON(w, runs)
+	case CSV:
+		return writeCSV(w, runs)
+	case Markdown:
+		return writeMarkdown(w, runs)
+	case Benchstat:
+		return writeBenchstat(w, runs)
+	default:
+		return CheckFormat(format)
+	}
+}
+
+// writeTable prints one aligned row per run, followed by the per-operation
+// breakdown of every run that has one.
+func writeTable(w io.Writer, runs []Run) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TEST\tOPS\tERRORS\tOPS/S\tP50\tP90\tP99\tP99.9\tMAX\tERROR RATE\tINTEGRITY")
+	for _, run := range runs {
+		writeTableRow(tw, run.Name(), run.Stats, run.Integrity())
+		for _, name := range operationNames(run.Result) {
+			writeTableRow(tw, "  "+name, run.ByOperation[name], "")
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, run := range runs {
+		if len(run.Summary) > 0 {
+			if err := writeSummary(w, run); err != nil {
+				return err
+			}
+		}
+	}
+	for _, run := range runs {
+		if run.Interrupted {
+			fmt.Fprintf(w, "%s was interrupted; its result is partial.\n", run.Name())
+		}
+	}
+	return nil
+}
+
+func writeTableRow(w io.Writer, name string, s database.Stats, integrity string) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%.1f\t%v\t%v\t%v\t%v\t%v\t%.2f%%\t%s\n",
+		name, s.Operations, s.Errors, s.Throughput,
+		round(s.P50Latency), round(s.P90Latency), round(s.P99Latency), round(s.P999Latency), round(s.MaxLatency),
+		s.ErrorRate*100, integrity)
+}
+
+// writeSummary prints the spread of every metric over the trials of run.
+func writeSummary(w io.Writer, run Run) error {
+	fmt.Fprintf(w, "\n%s over %d trials:\n", run.Name(), len(run.Trials))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METRIC\tMEAN\tSTDDEV\tCV\t95% CI")
+	for _, metric := range compare.Metrics {
+		s, ok := run.Summary[metric]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.1f%%\t%s - %s\n", metric,
+			formatMetric(metric, s.Mean), formatMetric(metric, s.StdDev), s.CV*100,
+			formatMetric(metric, s.CILow), formatMetric(metric, s.CIHigh))
+	}
+	return tw.Flush()
+}
+
+// formatMetric formats a value of a compare metric for display.
+func formatMetric(metric string, v float64) string {
+	switch metric {
+	case compare.Throughput:
+		return fmt.Sprintf("%.1f", v)
+	case compare.ErrorRate:
+		return fmt.Sprintf("%.3f%%", v*100)
+	default:
+		return round(time.Duration(v)).String()
+	}
+}
+
+func writeJSON(w io.Writer, runs []Run) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	for _, run := range runs {
+		if err := enc.Encode(run); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// csvHeader names the columns written by writeCSV. Latencies are in
+// milliseconds.
+var csvHeader = []string{
+	"db", "workload", "test", "trial", "operation",
+	"operations", "errors", "throughput", "error_rate",
+	"min_ms", "p50_ms", "p90_ms", "p95_ms", "p99_ms", "p999_ms", "p9999_ms", "max_ms", "avg_ms", "stddev_ms",
+	"retries", "gave_up", "integrity", "interrupted", "seed",
+}
+
+// writeCSV writes one row per run and one more per named operation, which
+// leave the run-level columns after the latencies empty. The trials of a
+// repeated run follow its pooled rows, numbered in the trial column.
+func writeCSV(w io.Writer, runs []Run) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, run := range runs {
+		if err := writeCSVResult(cw, run, "", run.Result); err != nil {
+			return err
+		}
+		for i, trial := range run.Trials {
+			if err := writeCSVResult(cw, run, strconv.Itoa(i+1), trial); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeCSVResult(cw *csv.Writer, run Run, trial string, result *database.Result) error {
+	row := append(csvStats(run, trial, "", result.Stats),
+		strconv.FormatInt(result.Retries, 10),
+		strconv.FormatInt(result.GaveUp, 10),
+		result.Integrity(),
+		strconv.FormatBool(result.Interrupted),
+		strconv.FormatInt(result.Seed, 10),
+	)
+	if err := cw.Write(row); err != nil {
+		return err
+	}
+	for _, name := range operationNames(result) {
+		row := append(csvStats(run, trial, name, result.ByOperation[name]), "", "", "", "", "")
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func csvStats(run Run, trial, operation string, s database.Stats) []string {
+	return []string{
+		run.DB, run.Workload, run.Test, trial, operation,
+		strconv.FormatInt(s.Operations, 10),
+		strconv.FormatInt(s.Errors, 10),
+		strconv.FormatFloat(s.Throughput, 'f', 2, 64),
+		strconv.FormatFloat(s.ErrorRate, 'f', 6, 64),
+		millis(s.MinLatency), millis(s.P50Latency), millis(s.P90Latency), millis(s.P95Latency),
+		millis(s.P99Latency), millis(s.P999Latency), millis(s.P9999Latency), millis(s.MaxLatency),
+		millis(s.AverageLatency), millis(s.StdDevLatency),
+	}
+}
+
+// writeMarkdown writes a GitHub-flavoured table with one row per run. The
+// throughput of a repeated run is followed by its coefficient of variation
+// over the trials.
+func writeMarkdown(w io.Writer, runs []Run) error {
+	fmt.Fprintln(w, "| Database | Workload | Test | Ops/s | p50 | p90 | p99 | p99.9 | Max | Error Rate | Integrity |")
+	fmt.Fprintln(w, "|---|---|---|--:|--:|--:|--:|--:|--:|--:|---|")
+	for _, run := range runs {
+		s := run.Stats
+		test := run.Test
+		if run.Interrupted {
+			test += " (interrupted)"
+		}
+		throughput := fmt.Sprintf("%.1f", s.Throughput)
+		if summary, ok := run.Summary[compare.Throughput]; ok {
+			throughput += fmt.Sprintf(" ± %.1f%%", summary.CV*100)
+		}
+		_, err := fmt.Fprintf(w, "| %s | %s | %s | %s | %v | %v | %v | %v | %v | %.2f%% | %s |\n",
+			run.DB, run.Workload, test, throughput,
+			round(s.P50Latency), round(s.P90Latency), round(s.P99Latency), round(s.P999Latency), round(s.MaxLatency),
+			s.ErrorRate*100, run.Integrity())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBenchstat writes the Go benchmark format understood by benchstat. Every
+// run and named operation is one benchmark line whose iteration count is the
+// number of successful operations. A repeated run writes its trials instead,
+// so that benchstat sees every trial as one sample.
+func writeBenchstat(w io.Writer, runs []Run) error {
+	for _, run := range runs {
+		name := fmt.Sprintf("Benchmark/db=%s/workload=%s/test=%s", run.DB, run.Workload, run.Test)
+		results := run.Trials
+		if len(results) == 0 {
+			results = []*database.Result{run.Result}
+		}
+		for _, result := range results {
+			if err := writeBenchstatLine(w, name, result.Stats); err != nil {
+				return err
+			}
+			for _, op := range operationNames(result) {
+				if err := writeBenchstatLine(w, name+"/op="+op, result.ByOperation[op]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func writeBenchstatLine(w io.Writer, name string, s database.Stats) error {
+	_, err := fmt.Fprintf(w, "%s %d %d ns/op %d p50-ns/op %d p90-ns/op %d p99-ns/op %d p99.9-ns/op %d max-ns/op %.2f ops/s %.6f errors/op\n",
+		name, s.Operations, s.AverageLatency.Nanoseconds(),
+		s.P50Latency.Nanoseconds(), s.P90Latency.Nanoseconds(), s.P99Latency.Nanoseconds(),
+		s.P999Latency.Nanoseconds(), s.MaxLatency.Nanoseconds(), s.Throughput, s.ErrorRate)
+	return err
+}
+
+// operationNames returns the names in result.ByOperation in order.
+func operationNames(result *database.Result) []string {
+	names := make([]string, 0, len(result.ByOperation))
+	for name := range result.ByOperation {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// round shortens a latency for display.
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
+
+func millis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
@@ -0,0 +1,173 @@
+// Package progress prints how a benchmark is getting on, so that a long run
+// or a slow setup does not look stuck.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"database-benchmark/internal/runner"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Reporter follows runs as a runner.Monitor and prints a progress line every
+// interval while one is running. It also prints the steps around the runs
+// and the progress of seeding.
+type Reporter struct {
+	w     io.Writer
+	every time.Duration
+
+	mu           sync.Mutex
+	start        time.Time
+	measureStart time.Time
+	measureEnd   time.Time
+	deadline     time.Time
+	// ops and latency cover the successful operations since the last line,
+	// errors and retries the whole run.
+	ops      int64
+	latency  *hdrhistogram.Histogram
+	lastLine time.Time
+	errors   int64
+	retries  int64
+	// seedTable is the table being seeded and lastSeed when its progress
+	// was last printed.
+	seedTable string
+	lastSeed  time.Time
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New returns a reporter that prints to w. A progress line is printed every
+// interval during a run; zero prints none.
+func New(w io.Writer, every time.Duration) *Reporter {
+	return &Reporter{
+		w:       w,
+		every:   every,
+		latency: hdrhistogram.New(1, runner.MaxLatency, 3),
+	}
+}
+
+// Printf prints a line about the benchmark, such as the step it is at.
+func (r *Reporter) Printf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.w, format+"\n", args...)
+}
+
+// Seeding prints that done of the total rows of table have been inserted. It
+// is a database.SeedProgress and prints at most one line per interval, plus
+// one once a table is complete.
+func (r *Reporter) Seeding(table string, done, total int) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if table != r.seedTable {
+		r.seedTable = table
+		r.lastSeed = now
+	}
+	if done < total && (r.every <= 0 || now.Sub(r.lastSeed) < r.every) {
+		return
+	}
+	r.lastSeed = now
+	fmt.Fprintf(r.w, "Seeding %s: %d/%d rows (%.0f%%)\n", table, done, total, float64(done)/float64(total)*100)
+}
+
+// Start starts printing progress lines for a new run.
+func (r *Reporter) Start(measureStart, measureEnd, deadline time.Time) {
+	now := time.Now()
+	r.mu.Lock()
+	r.start = now
+	r.measureStart = measureStart
+	r.measureEnd = measureEnd
+	r.deadline = deadline
+	r.ops = 0
+	r.latency.Reset()
+	r.lastLine = now
+	r.errors = 0
+	r.retries = 0
+	r.mu.Unlock()
+
+	if r.every <= 0 {
+		return
+	}
+	r.done = make(chan struct{})
+	r.wg.Add(1)
+	go r.loop(r.done)
+}
+
+// Begin does nothing; the reporter only counts completed operations.
+func (r *Reporter) Begin() {}
+
+// Finish counts a completed operation.
+func (r *Reporter) Finish(latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errors++
+		return
+	}
+	r.ops++
+	r.latency.RecordValue(runner.LatencyMicros(latency))
+}
+
+// Abandon does nothing; an abandoned operation neither succeeded nor failed.
+func (r *Reporter) Abandon() {}
+
+// Retry counts a retried attempt.
+func (r *Reporter) Retry(class string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retries++
+}
+
+// End stops the progress lines of the run.
+func (r *Reporter) End() {
+	if r.done == nil {
+		return
+	}
+	close(r.done)
+	r.wg.Wait()
+	r.done = nil
+}
+
+func (r *Reporter) loop(done <-chan struct{}) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.every)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			r.line(now)
+		case <-done:
+			return
+		}
+	}
+}
+
+// line prints the progress of the run at now and starts a new interval.
+func (r *Reporter) line(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var rate float64
+	if elapsed := now.Sub(r.lastLine); elapsed > 0 {
+		rate = float64(r.ops) / elapsed.Seconds()
+	}
+	p99 := "-"
+	if r.latency.TotalCount() > 0 {
+		p99 = (time.Duration(r.latency.ValueAtQuantile(99)) * time.Microsecond).String()
+	}
+	left := r.deadline.Sub(now)
+	if left < 0 {
+		left = 0
+	}
+	fmt.Fprintf(r.w, "%-8s %v elapsed, %v left: %.1f ops/s, p99 %s, %d errors, %d retries\n",
+		runner.Phase(now, r.measureStart, r.measureEnd), now.Sub(r.start).Round(time.Second), left.Round(time.Second),
+		rate, p99, r.errors, r.retries)
+	r.ops = 0
+	r.latency.Reset()
+	r.lastLine = now
+}
@@ -0,0 +1,272 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"database-benchmark/internal/store"
+)
+
+// Test is one workload and test of the matrix.
+type Test struct {
+	Workload string
+	Test     string
+}
+
+// Matrix is the set of tests expected to have run on every database.
+type Matrix struct {
+	DBs   []string
+	Tests []Test
+}
+
+// row is a test of the matrix at one concurrency. Concurrency is zero for
+// runs that have no metadata to tell it.
+type row struct {
+	Workload    string
+	Test        string
+	Concurrency int
+}
+
+// cell is the latest run of one row on one database, or nil when the test
+// has not run there at that concurrency.
+type cell struct {
+	entry  *store.Entry
+	failed string
+}
+
+// WriteMarkdown writes the matrix of m as a Markdown document in the layout of
+// test_results.md, using the latest of entries for every cell. A test gets a
+// row for every concurrency it ran at. Links to run directories are relative
+// to base, the directory the document is written to.
+func WriteMarkdown(w io.Writer, m Matrix, entries []store.Entry, base string, generated time.Time) error {
+	cells := make(map[string]*cell)
+	key := func(db string, r row) string {
+		return fmt.Sprintf("%s/%s/%s/%d", db, r.Workload, r.Test, r.Concurrency)
+	}
+	levels := make(map[Test][]int)
+	for i := range entries {
+		entry := &entries[i]
+		t := Test{entry.Run.Workload, entry.Run.Test}
+		r := row{t.Workload, t.Test, concurrency(entry)}
+		k := key(entry.Run.DB, r)
+		if c, ok := cells[k]; ok && !newer(entry, c.entry) {
+			continue
+		}
+		if !slices.Contains(levels[t], r.Concurrency) {
+			levels[t] = append(levels[t], r.Concurrency)
+		}
+		cells[k] = &cell{entry: entry, failed: failure(entry)}
+	}
+	// A test that never ran still gets a row of missing cells.
+	var rows []row
+	for _, t := range m.Tests {
+		ls := levels[t]
+		if len(ls) == 0 {
+			ls = []int{0}
+		}
+		slices.Sort(ls)
+		for _, level := range ls {
+			rows = append(rows, row{t.Workload, t.Test, level})
+		}
+	}
+	link := func(c *cell, text string) string {
+		if c.entry.Dir == "" {
+			return text
+		}
+		path := c.entry.Dir
+		if rel, err := filepath.Rel(base, path); err == nil {
+			path = rel
+		}
+		return fmt.Sprintf("[%s](%s)", text, filepath.ToSlash(path))
+	}
+
+	var b strings.Builder
+	fmt.Fprintln(&b, "# Database Benchmark Test Results")
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "Generated on %s from the run store by `benchmark-runner summary`. Every cell is the latest run of the test on that database at that concurrency and links to its run directory.\n",
+		generated.Format("2006-01-02 15:04 MST"))
+
+	// header starts a matrix table whose database columns hold numbers
+	// when numeric is set.
+	header := func(numeric bool, extra ...string) {
+		cols := append([]string{"Workload", "Test", "Concurrency"}, m.DBs...)
+		cols = append(cols, extra...)
+		align := "---|"
+		if numeric {
+			align = "--:|"
+		}
+		fmt.Fprintf(&b, "| %s |\n|---|---|--:|%s\n", strings.Join(cols, " | "), strings.Repeat(align, len(m.DBs))+strings.Repeat("---|", len(extra)))
+	}
+
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "## Throughput (ops/s)")
+	fmt.Fprintln(&b)
+	header(true, "Winner")
+	for _, r := range rows {
+		cols := []string{r.Workload, r.Test, r.level()}
+		winner, best := "-", 0.0
+		for _, db := range m.DBs {
+			c := cells[key(db, r)]
+			switch {
+			case c == nil:
+				cols = append(cols, "missing")
+			case c.failed != "":
+				cols = append(cols, link(c, "failed"))
+			default:
+				cols = append(cols, link(c, formatRate(c.entry.Run.Throughput)))
+				if c.entry.Run.Throughput > best {
+					winner, best = "**"+db+"**", c.entry.Run.Throughput
+				}
+			}
+		}
+		fmt.Fprintf(&b, "| %s | %s |\n", strings.Join(cols, " | "), winner)
+	}
+
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "## p99 Latency")
+	fmt.Fprintln(&b)
+	header(true)
+	for _, r := range rows {
+		cols := []string{r.Workload, r.Test, r.level()}
+		for _, db := range m.DBs {
+			c := cells[key(db, r)]
+			switch {
+			case c == nil:
+				cols = append(cols, "missing")
+			case c.failed != "":
+				cols = append(cols, link(c, "failed"))
+			default:
+				cols = append(cols, link(c, formatMillis(millis(c.entry.Run.P99Latency))))
+			}
+		}
+		fmt.Fprintf(&b, "| %s |\n", strings.Join(cols, " | "))
+	}
+
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "## Data Integrity")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "`ok` and `FAILED` are the result of the test's integrity check; `-` means the test has no check.")
+	fmt.Fprintln(&b)
+	header(false)
+	for _, r := range rows {
+		cols := []string{r.Workload, r.Test, r.level()}
+		for _, db := range m.DBs {
+			c := cells[key(db, r)]
+			if c == nil {
+				cols = append(cols, "missing")
+				continue
+			}
+			cols = append(cols, link(c, c.entry.Run.Integrity()))
+		}
+		fmt.Fprintf(&b, "| %s |\n", strings.Join(cols, " | "))
+	}
+
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "## Runs")
+	for _, r := range rows {
+		fmt.Fprintln(&b)
+		fmt.Fprintf(&b, "### %s\n\n", r)
+		fmt.Fprintln(&b, "| Database | Ops/s | p50 | p99 | Error Rate | Integrity | Run | Command |")
+		fmt.Fprintln(&b, "|---|--:|--:|--:|--:|---|---|---|")
+		for _, db := range m.DBs {
+			c := cells[key(db, r)]
+			if c == nil {
+				fmt.Fprintf(&b, "| %s | | | | | | missing | |\n", db)
+				continue
+			}
+			run := c.entry.Run
+			name, command := "-", ""
+			if c.entry.Dir != "" {
+				name = link(c, filepath.Base(c.entry.Dir))
+			}
+			if c.entry.Metadata != nil {
+				command = "`" + strings.Join(append([]string{"benchmark-runner", "run"}, c.entry.Metadata.Args...), " ") + "`"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %.2f%% | %s | %s | %s |\n",
+				db, formatRate(run.Throughput), formatMillis(millis(run.P50Latency)), formatMillis(millis(run.P99Latency)),
+				run.ErrorRate*100, c.entry.Run.Integrity(), name, command)
+		}
+	}
+
+	var notes []string
+	for _, r := range rows {
+		for _, db := range m.DBs {
+			c := cells[key(db, r)]
+			switch {
+			case c == nil:
+				notes = append(notes, fmt.Sprintf("%s on %s: missing, no run in the store.", r, db))
+			case c.failed != "":
+				notes = append(notes, fmt.Sprintf("%s on %s: failed, %s.", r, db, c.failed))
+			}
+		}
+	}
+	if len(notes) > 0 {
+		fmt.Fprintln(&b)
+		fmt.Fprintln(&b, "## Notes")
+		fmt.Fprintln(&b)
+		for _, note := range notes {
+			fmt.Fprintf(&b, "- %s\n", note)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// level is the concurrency column of r.
+func (r row) level() string {
+	if r.Concurrency == 0 {
+		return "-"
+	}
+	return strconv.Itoa(r.Concurrency)
+}
+
+// String names r in headings and notes.
+func (r row) String() string {
+	if r.Concurrency == 0 {
+		return r.Workload + " / " + r.Test
+	}
+	return fmt.Sprintf("%s / %s at concurrency %d", r.Workload, r.Test, r.Concurrency)
+}
+
+// concurrency returns the concurrency entry ran at, or zero if its metadata
+// is missing.
+func concurrency(entry *store.Entry) int {
+	if entry.Metadata == nil {
+		return 0
+	}
+	return entry.Metadata.Options.Concurrency
+}
+
+// newer reports whether a started after b. Runs without metadata count as
+// older than any run with it.
+func newer(a, b *store.Entry) bool {
+	switch {
+	case a.Metadata == nil:
+		return false
+	case b.Metadata == nil:
+		return true
+	default:
+		return a.Metadata.StartedAt.After(b.Metadata.StartedAt)
+	}
+}
+
+// failure explains why the run in entry does not count, or returns "" if it
+// does. A failed run keeps its cell but cannot win.
+func failure(entry *store.Entry) string {
+	run := entry.Run
+	switch {
+	case run.Interrupted:
+		return "the run was interrupted"
+	case run.Verified && !run.DataIntegrity:
+		return "the integrity check failed"
+	case run.Operations == 0:
+		return "no operation succeeded"
+	}
+	return ""
+}
@@ -0,0 +1,343 @@
+// Package report renders stored benchmark runs as a single HTML page with
+// inline SVG charts, which can be opened or shared without any other files.
+package report
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"database-benchmark/internal/output"
+	"database-benchmark/internal/store"
+)
+
+// dbOrder sorts the databases of a comparison; others follow by name.
+var dbOrder = map[string]int{"postgres": 0, "mysql": 1, "mongo": 2}
+
+// Write renders entries as a report generated at generated. Runs are grouped
+// by workload and test, and the runs of a group are compared with each other.
+func Write(w io.Writer, entries []store.Entry, generated time.Time) error {
+	page := page{Generated: generated.Format("2006-01-02 15:04:05 MST")}
+
+	sorted := make([]store.Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Run, sorted[j].Run
+		if a.Workload != b.Workload {
+			return a.Workload < b.Workload
+		}
+		if a.Test != b.Test {
+			return a.Test < b.Test
+		}
+		return dbLess(a.DB, b.DB)
+	})
+
+	for start := 0; start < len(sorted); {
+		end := start + 1
+		for end < len(sorted) && sorted[end].Run.Workload == sorted[start].Run.Workload && sorted[end].Run.Test == sorted[start].Run.Test {
+			end++
+		}
+		g, err := newGroup(sorted[start:end])
+		if err != nil {
+			return err
+		}
+		page.Groups = append(page.Groups, g)
+		start = end
+	}
+	for _, g := range page.Groups {
+		page.Runs = append(page.Runs, g.Runs...)
+	}
+	return pageTemplate.Execute(w, page)
+}
+
+func dbLess(a, b string) bool {
+	ra, okA := dbOrder[a]
+	rb, okB := dbOrder[b]
+	switch {
+	case okA && okB:
+		return ra < rb
+	case okA != okB:
+		return okA
+	default:
+		return a < b
+	}
+}
+
+type page struct {
+	Generated string
+	Runs      []runView
+	Groups    []group
+}
+
+// group is the section of the report for one workload and test.
+type group struct {
+	Workload, Test string
+	Runs           []runView
+	Charts         []template.HTML
+}
+
+type runView struct {
+	Label      string
+	DB         string
+	Workload   string
+	Test       string
+	Started    string
+	Dir        string
+	Throughput string
+	P50, P99   string
+	P999, Max  string
+	ErrorRate  string
+	Integrity  string
+	Note       string
+	Operations []operationView
+}
+
+type operationView struct {
+	Name       string
+	Operations int64
+	Throughput string
+	P50, P99   string
+	P999       string
+	ErrorRate  string
+}
+
+func newGroup(entries []store.Entry) (group, error) {
+	g := group{Workload: entries[0].Run.Workload, Test: entries[0].Run.Test}
+	labels := runLabels(entries, func(run output.Run) string { return run.DB })
+
+	throughput := barChart{title: "Throughput", yLabel: "ops/s", names: []string{"ops/s"}, yFormat: formatRate}
+	latency := barChart{title: "Latency", yLabel: "ms", names: []string{"p50", "p99", "p99.9"}, yFormat: formatMillis}
+	for i, entry := range entries {
+		result := entry.Run.Result
+		g.Runs = append(g.Runs, newRunView(labels[i], entry))
+		throughput.bars = append(throughput.bars, bar{label: labels[i], values: []float64{result.Throughput}})
+		latency.bars = append(latency.bars, bar{label: labels[i], values: []float64{
+			millis(result.P50Latency), millis(result.P99Latency), millis(result.P999Latency),
+		}})
+	}
+
+	lines, err := lineCharts(entries, labels)
+	if err != nil {
+		return group{}, err
+	}
+	g.Charts = append([]template.HTML{throughput.render(), latency.render()}, lines...)
+	return g, nil
+}
+
+// lineCharts overlays the throughput and p99 latency over time and the
+// latency spectrum of entries, one line per run named by labels.
+func lineCharts(entries []store.Entry, labels []string) ([]template.HTML, error) {
+	overTime := lineChart{title: "Throughput over time", xLabel: "seconds", yLabel: "ops/s", xFormat: formatSeconds, yFormat: formatRate}
+	p99OverTime := lineChart{title: "p99 latency over time", xLabel: "seconds", yLabel: "ms", xFormat: formatSeconds, yFormat: formatMillis}
+	spectrum := lineChart{title: "Latency by percentile", xLabel: "percentile", yLabel: "ms", xTicks: spectrumTicks, yFormat: formatMillis}
+	for i, entry := range entries {
+		result := entry.Run.Result
+		rate := series{name: labels[i]}
+		p99 := series{name: labels[i]}
+		for _, interval := range result.Intervals {
+			x := (interval.Start + interval.Duration).Seconds()
+			rate.points = append(rate.points, point{x, interval.Throughput})
+			p99.points = append(p99.points, point{x, millis(interval.P99Latency)})
+		}
+		overTime.series = append(overTime.series, rate)
+		p99OverTime.series = append(p99OverTime.series, p99)
+
+		points, err := spectrumPoints(entry)
+		if err != nil {
+			return nil, err
+		}
+		spectrum.series = append(spectrum.series, series{name: labels[i], points: points})
+	}
+	return []template.HTML{overTime.render(), p99OverTime.render(), spectrum.render()}, nil
+}
+
+// runLabels labels runs with name, adding the start time or a number where
+// several runs share a name.
+func runLabels(entries []store.Entry, name func(run output.Run) string) []string {
+	count := make(map[string]int)
+	for _, entry := range entries {
+		count[name(entry.Run)]++
+	}
+	seen := make(map[string]int)
+	labels := make([]string, len(entries))
+	for i, entry := range entries {
+		n := name(entry.Run)
+		seen[n]++
+		switch {
+		case count[n] == 1:
+			labels[i] = n
+		case entry.Metadata != nil:
+			labels[i] = n + " " + entry.Metadata.StartedAt.Format("01-02 15:04:05")
+		default:
+			labels[i] = fmt.Sprintf("%s #%d", n, seen[n])
+		}
+	}
+	return labels
+}
+
+func newRunView(label string, entry store.Entry) runView {
+	run := entry.Run
+	v := runView{
+		Label:      label,
+		DB:         run.DB,
+		Workload:   run.Workload,
+		Test:       run.Test,
+		Started:    "-",
+		Dir:        entry.Dir,
+		Throughput: formatRate(run.Throughput),
+		P50:        formatMillis(millis(run.P50Latency)),
+		P99:        formatMillis(millis(run.P99Latency)),
+		P999:       formatMillis(millis(run.P999Latency)),
+		Max:        formatMillis(millis(run.MaxLatency)),
+		ErrorRate:  fmt.Sprintf("%.2f%%", run.ErrorRate*100),
+		Integrity:  run.Integrity(),
+	}
+	if entry.Metadata != nil {
+		v.Started = entry.Metadata.StartedAt.Format("2006-01-02 15:04:05")
+	}
+	switch {
+	case run.Interrupted:
+		v.Note = "interrupted; partial result"
+	case len(run.Trials) > 1:
+		v.Note = fmt.Sprintf("pooled over %d trials", len(run.Trials))
+	}
+
+	names := make([]string, 0, len(run.ByOperation))
+	for name := range run.ByOperation {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := run.ByOperation[name]
+		v.Operations = append(v.Operations, operationView{
+			Name:       name,
+			Operations: s.Operations,
+			Throughput: formatRate(s.Throughput),
+			P50:        formatMillis(millis(s.P50Latency)),
+			P99:        formatMillis(millis(s.P99Latency)),
+			P999:       formatMillis(millis(s.P999Latency)),
+			ErrorRate:  fmt.Sprintf("%.2f%%", s.ErrorRate*100),
+		})
+	}
+	return v
+}
+
+// The percentile spectrum puts percentile q at log10(1/(1-q)), so that every
+// additional nine gets the same width.
+var spectrumTicks = []tick{
+	{0, "0%"}, {1, "90%"}, {2, "99%"}, {3, "99.9%"}, {4, "99.99%"}, {5, "99.999%"},
+}
+
+// spectrumPoints returns the latency spectrum of entry, read from its stored
+// histogram when it has one and made of the result's percentiles otherwise.
+func spectrumPoints(entry store.Entry) ([]point, error) {
+	if entry.Dir != "" {
+		hists, err := store.ReadHistogramLog(filepath.Join(entry.Dir, store.HistogramFile))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		for _, h := range hists {
+			if h.Tag() != "total" || h.TotalCount() == 0 {
+				continue
+			}
+			var points []point
+			for x := 0.0; x <= 5; x += 0.05 {
+				q := 1 - math.Pow(10, -x)
+				points = append(points, point{x, float64(h.ValueAtQuantile(q*100)) / 1000})
+			}
+			return points, nil
+		}
+	}
+
+	s := entry.Run.Stats
+	if s.Operations == 0 {
+		return nil, nil
+	}
+	return []point{
+		{0, millis(s.MinLatency)},
+		{math.Log10(2), millis(s.P50Latency)},
+		{1, millis(s.P90Latency)},
+		{math.Log10(20), millis(s.P95Latency)},
+		{2, millis(s.P99Latency)},
+		{3, millis(s.P999Latency)},
+		{4, millis(s.P9999Latency)},
+	}, nil
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func formatMillis(v float64) string {
+	switch {
+	case v >= 100:
+		return fmt.Sprintf("%.0f ms", v)
+	case v >= 10:
+		return fmt.Sprintf("%.1f ms", v)
+	default:
+		return fmt.Sprintf("%.2f ms", v)
+	}
+}
+
+func formatRate(v float64) string {
+	return fmt.Sprintf("%.1f", v)
+}
+
+func formatSeconds(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64) + "s"
+}
+
+// styles is the style sheet shared by every page.
+const styles = `<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 1000px; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; font-size: 14px; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child, td.text { text-align: left; }
+.failed { color: #c0392b; font-weight: bold; }
+.note { color: #888; }
+svg { display: block; margin: 1em 0; }
+</style>`
+
+var pageTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Database Benchmark Report</title>
+` + styles + `
+</head>
+<body>
+<h1>Database Benchmark Report</h1>
+<p class="note">Generated {{.Generated}} from {{len .Runs}} runs.</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Run</th><th>Workload</th><th>Test</th><th>Ops/s</th><th>p50</th><th>p99</th><th>p99.9</th><th>Max</th><th>Error Rate</th><th>Integrity</th><th>Started</th><th>Directory</th></tr>
+{{- range .Runs}}
+<tr><td>{{.Label}}</td><td class="text">{{.Workload}}</td><td class="text">{{.Test}}</td><td>{{.Throughput}}</td><td>{{.P50}}</td><td>{{.P99}}</td><td>{{.P999}}</td><td>{{.Max}}</td><td>{{.ErrorRate}}</td><td{{if eq .Integrity "FAILED"}} class="failed"{{end}}>{{.Integrity}}</td><td>{{.Started}}</td><td class="text">{{if .Dir}}<code>{{.Dir}}</code>{{end}}{{if .Note}} <span class="note">({{.Note}})</span>{{end}}</td></tr>
+{{- end}}
+</table>
+
+{{range .Groups}}
+<h2>{{.Workload}} / {{.Test}}</h2>
+{{range .Charts}}{{.}}
+{{end}}
+{{- range .Runs}}{{if .Operations}}
+<h3>{{.Label}} by operation</h3>
+<table>
+<tr><th>Operation</th><th>Ops</th><th>Ops/s</th><th>p50</th><th>p99</th><th>p99.9</th><th>Error Rate</th></tr>
+{{- range .Operations}}
+<tr><td>{{.Name}}</td><td>{{.Operations}}</td><td>{{.Throughput}}</td><td>{{.P50}}</td><td>{{.P99}}</td><td>{{.P999}}</td><td>{{.ErrorRate}}</td></tr>
+{{- end}}
+</table>
+{{end}}{{end}}
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,245 @@
+package report
+
+import (
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"database-benchmark/internal/output"
+	"database-benchmark/internal/store"
+)
+
+// downloads lists the files of a run directory that the dashboard serves.
+var downloads = []string{store.ResultFile, store.MetadataFile, store.ConfigFile, store.HistogramFile, store.IntervalFile}
+
+// Handler serves a dashboard over the run store in root. The store is read on
+// every request, so new runs show up without a restart.
+//
+//	/                     runs, filtered by ?db=, ?workload= and ?test=
+//	/overlay?run=NAME...  time series of the selected runs on shared charts
+//	/runs/NAME/           the report of one run
+//	/runs/NAME/FILE       a raw file of the run, such as result.json
+func Handler(root string) http.Handler {
+	s := &server{root: root}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.index)
+	mux.HandleFunc("GET /overlay", s.overlay)
+	mux.HandleFunc("GET /runs/{name}/{$}", s.run)
+	mux.HandleFunc("GET /runs/{name}/{file}", s.download)
+	return mux
+}
+
+type server struct {
+	root string
+}
+
+type indexPage struct {
+	DB, Workload, Test    string
+	DBs, Workloads, Tests []string
+	Runs                  []indexRow
+}
+
+type indexRow struct {
+	Name string
+	runView
+	Downloads []string
+}
+
+func (s *server) index(w http.ResponseWriter, r *http.Request) {
+	entries, ok := s.list(w)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	page := indexPage{
+		DB:       query.Get("db"),
+		Workload: query.Get("workload"),
+		Test:     query.Get("test"),
+	}
+	// Newest first.
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		run := entry.Run
+		page.DBs = appendUnique(page.DBs, run.DB)
+		page.Workloads = appendUnique(page.Workloads, run.Workload)
+		page.Tests = appendUnique(page.Tests, run.Test)
+		if page.DB != "" && run.DB != page.DB ||
+			page.Workload != "" && run.Workload != page.Workload ||
+			page.Test != "" && run.Test != page.Test {
+			continue
+		}
+		row := indexRow{Name: filepath.Base(entry.Dir), runView: newRunView(run.DB, entry)}
+		for _, file := range downloads {
+			if fileExists(filepath.Join(entry.Dir, file)) {
+				row.Downloads = append(row.Downloads, file)
+			}
+		}
+		page.Runs = append(page.Runs, row)
+	}
+	sort.Strings(page.DBs)
+	sort.Strings(page.Workloads)
+	sort.Strings(page.Tests)
+	render(w, indexTemplate, page)
+}
+
+type overlayPage struct {
+	Runs   []runView
+	Charts []template.HTML
+}
+
+func (s *server) overlay(w http.ResponseWriter, r *http.Request) {
+	entries, ok := s.list(w)
+	if !ok {
+		return
+	}
+	var selected []store.Entry
+	for _, entry := range entries {
+		if slices.Contains(r.URL.Query()["run"], filepath.Base(entry.Dir)) {
+			selected = append(selected, entry)
+		}
+	}
+	if len(selected) == 0 {
+		http.Error(w, "no runs selected", http.StatusBadRequest)
+		return
+	}
+
+	labels := runLabels(selected, output.Run.Name)
+	page := overlayPage{}
+	for i, entry := range selected {
+		page.Runs = append(page.Runs, newRunView(labels[i], entry))
+	}
+	charts, err := lineCharts(selected, labels)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	page.Charts = charts
+	render(w, overlayTemplate, page)
+}
+
+func (s *server) run(w http.ResponseWriter, r *http.Request) {
+	entry, ok := s.find(w, r.PathValue("name"))
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := Write(w, []store.Entry{entry}, time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *server) download(w http.ResponseWriter, r *http.Request) {
+	file := r.PathValue("file")
+	if !slices.Contains(downloads, file) {
+		http.NotFound(w, r)
+		return
+	}
+	entry, ok := s.find(w, r.PathValue("name"))
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Disposition", "attachment; filename="+filepath.Base(entry.Dir)+"-"+file)
+	http.ServeFile(w, r, filepath.Join(entry.Dir, file))
+}
+
+// list reads the store, reporting a failure to the client.
+func (s *server) list(w http.ResponseWriter) ([]store.Entry, bool) {
+	entries, err := store.List(s.root)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return entries, true
+}
+
+// find looks up the run directory called name. Only names of runs in the
+// store are accepted, so requests cannot reach other files.
+func (s *server) find(w http.ResponseWriter, name string) (store.Entry, bool) {
+	entries, ok := s.list(w)
+	if !ok {
+		return store.Entry{}, false
+	}
+	for _, entry := range entries {
+		if filepath.Base(entry.Dir) == name {
+			return entry, true
+		}
+	}
+	http.Error(w, "no such run: "+name, http.StatusNotFound)
+	return store.Entry{}, false
+}
+
+func render(w http.ResponseWriter, t *template.Template, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := t.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func appendUnique(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Benchmark Runs</title>
+` + styles + `
+</head>
+<body>
+<h1>Benchmark Runs</h1>
+<form method="get" action="/">
+<select name="db"><option value="">all databases</option>{{range .DBs}}<option{{if eq . $.DB}} selected{{end}}>{{.}}</option>{{end}}</select>
+<select name="workload"><option value="">all workloads</option>{{range .Workloads}}<option{{if eq . $.Workload}} selected{{end}}>{{.}}</option>{{end}}</select>
+<select name="test"><option value="">all tests</option>{{range .Tests}}<option{{if eq . $.Test}} selected{{end}}>{{.}}</option>{{end}}</select>
+<button type="submit">Filter</button>
+</form>
+{{if .Runs}}
+<form method="get" action="/overlay">
+<table>
+<tr><th></th><th>Run</th><th>Database</th><th>Workload</th><th>Test</th><th>Ops/s</th><th>p50</th><th>p99</th><th>Error Rate</th><th>Integrity</th><th>Started</th><th>Files</th></tr>
+{{- range .Runs}}
+<tr><td><input type="checkbox" name="run" value="{{.Name}}"></td><td class="text"><a href="/runs/{{.Name}}/">{{.Name}}</a>{{if .Note}} <span class="note">({{.Note}})</span>{{end}}</td><td class="text">{{.DB}}</td><td class="text">{{.Workload}}</td><td class="text">{{.Test}}</td><td>{{.Throughput}}</td><td>{{.P50}}</td><td>{{.P99}}</td><td>{{.ErrorRate}}</td><td{{if eq .Integrity "FAILED"}} class="failed"{{end}}>{{.Integrity}}</td><td>{{.Started}}</td><td class="text">{{$name := .Name}}{{range .Downloads}}<a href="/runs/{{$name}}/{{.}}">{{.}}</a> {{end}}</td></tr>
+{{- end}}
+</table>
+<button type="submit">Overlay selected runs</button>
+</form>
+{{else}}
+<p class="note">No runs match.</p>
+{{end}}
+</body>
+</html>
+`))
+
+var overlayTemplate = template.Must(template.New("overlay").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Benchmark Runs</title>
+` + styles + `
+</head>
+<body>
+<p><a href="/">All runs</a></p>
+<table>
+<tr><th>Run</th><th>Ops/s</th><th>p50</th><th>p99</th><th>p99.9</th><th>Max</th><th>Error Rate</th><th>Integrity</th><th>Started</th></tr>
+{{- range .Runs}}
+<tr><td>{{.Label}}</td><td>{{.Throughput}}</td><td>{{.P50}}</td><td>{{.P99}}</td><td>{{.P999}}</td><td>{{.Max}}</td><td>{{.ErrorRate}}</td><td{{if eq .Integrity "FAILED"}} class="failed"{{end}}>{{.Integrity}}</td><td>{{.Started}}</td></tr>
+{{- end}}
+</table>
+{{range .Charts}}{{.}}
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,233 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"strings"
+)
+
+// Chart geometry in SVG user units.
+const (
+	chartWidth   = 720
+	chartHeight  = 300
+	marginLeft   = 64
+	marginRight  = 16
+	marginTop    = 28
+	marginBottom = 44
+	legendRow    = 18
+)
+
+// palette colours the series of a chart in order.
+var palette = []string{"#336791", "#e48e00", "#4db33d", "#c0392b", "#8e44ad", "#16a085", "#7f8c8d", "#d35400"}
+
+func color(i int) string {
+	return palette[i%len(palette)]
+}
+
+type point struct {
+	x, y float64
+}
+
+type series struct {
+	name   string
+	points []point
+}
+
+type tick struct {
+	value float64
+	label string
+}
+
+// lineChart describes a chart of one line per series. Without xTicks, the x
+// axis gets evenly spaced ticks formatted with xFormat.
+type lineChart struct {
+	title, xLabel, yLabel string
+	series                []series
+	xTicks                []tick
+	xFormat               func(float64) string
+	yFormat               func(float64) string
+}
+
+// render draws the chart as an inline SVG element.
+func (c lineChart) render() template.HTML {
+	var minX, maxX, maxY float64
+	minX = math.Inf(1)
+	for _, s := range c.series {
+		for _, p := range s.points {
+			minX = math.Min(minX, p.x)
+			maxX = math.Max(maxX, p.x)
+			maxY = math.Max(maxY, p.y)
+		}
+	}
+	if math.IsInf(minX, 1) {
+		return emptyChart(c.title)
+	}
+	xTicks := c.xTicks
+	if xTicks == nil {
+		xTicks = niceTicks(minX, maxX, c.xFormat)
+	}
+	if len(xTicks) > 0 {
+		minX = math.Min(minX, xTicks[0].value)
+		maxX = math.Max(maxX, xTicks[len(xTicks)-1].value)
+	}
+	if maxX == minX {
+		maxX = minX + 1
+	}
+	yTicks := niceTicks(0, maxY, c.yFormat)
+	maxY = yTicks[len(yTicks)-1].value
+
+	height := chartHeight + legendRow*len(c.series)
+	var b strings.Builder
+	openSVG(&b, height, c.title)
+	x := func(v float64) float64 {
+		return marginLeft + (v-minX)/(maxX-minX)*(chartWidth-marginLeft-marginRight)
+	}
+	y := func(v float64) float64 {
+		return chartHeight - marginBottom - v/maxY*(chartHeight-marginTop-marginBottom)
+	}
+	axes(&b, xTicks, yTicks, x, y, c.xLabel, c.yLabel)
+	for i, s := range c.series {
+		var path strings.Builder
+		for j, p := range s.points {
+			cmd := "L"
+			if j == 0 {
+				cmd = "M"
+			}
+			fmt.Fprintf(&path, "%s%.1f,%.1f", cmd, x(p.x), y(p.y))
+		}
+		fmt.Fprintf(&b, `<path d="%s" fill="none" stroke="%s" stroke-width="1.5"/>`, path.String(), color(i))
+	}
+	legend(&b, c.series)
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+// bar is one group of bars, with one value per name of a barChart.
+type bar struct {
+	label  string
+	values []float64
+}
+
+// barChart describes a vertical bar chart. Each bar group has one bar per
+// name, coloured like a series.
+type barChart struct {
+	title, yLabel string
+	names         []string
+	bars          []bar
+	yFormat       func(float64) string
+}
+
+func (c barChart) render() template.HTML {
+	var maxY float64
+	for _, group := range c.bars {
+		for _, v := range group.values {
+			maxY = math.Max(maxY, v)
+		}
+	}
+	if len(c.bars) == 0 {
+		return emptyChart(c.title)
+	}
+	yTicks := niceTicks(0, maxY, c.yFormat)
+	maxY = yTicks[len(yTicks)-1].value
+
+	legendSeries := make([]series, len(c.names))
+	for i, name := range c.names {
+		legendSeries[i] = series{name: name}
+	}
+	if len(c.names) == 1 {
+		legendSeries = nil
+	}
+	height := chartHeight + legendRow*len(legendSeries)
+	var b strings.Builder
+	openSVG(&b, height, c.title)
+	y := func(v float64) float64 {
+		return chartHeight - marginBottom - v/maxY*(chartHeight-marginTop-marginBottom)
+	}
+	groupWidth := float64(chartWidth-marginLeft-marginRight) / float64(len(c.bars))
+	barWidth := groupWidth * 0.8 / float64(len(c.names))
+	axes(&b, nil, yTicks, nil, y, "", c.yLabel)
+	for i, group := range c.bars {
+		left := marginLeft + groupWidth*float64(i) + groupWidth*0.1
+		for j, v := range group.values {
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s</title></rect>`,
+				left+barWidth*float64(j), y(v), barWidth, y(0)-y(v), color(j), html.EscapeString(c.yFormat(v)))
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`,
+			left+groupWidth*0.4, chartHeight-marginBottom+16, html.EscapeString(group.label))
+	}
+	legend(&b, legendSeries)
+	b.WriteString("</svg>")
+	return template.HTML(b.String())
+}
+
+func openSVG(b *strings.Builder, height int, title string) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" font-family="sans-serif" font-size="11">`,
+		chartWidth, height, chartWidth, height)
+	fmt.Fprintf(b, `<text x="%d" y="16" font-size="13" font-weight="bold">%s</text>`, marginLeft, html.EscapeString(title))
+}
+
+// axes draws the grid lines and tick labels. Without an x scale, only the y
+// axis is drawn.
+func axes(b *strings.Builder, xTicks, yTicks []tick, x, y func(float64) float64, xLabel, yLabel string) {
+	for _, t := range yTicks {
+		fmt.Fprintf(b, `<line x1="%d" x2="%d" y1="%.1f" y2="%.1f" stroke="#ddd"/>`, marginLeft, chartWidth-marginRight, y(t.value), y(t.value))
+		fmt.Fprintf(b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%s</text>`, marginLeft-6, y(t.value), html.EscapeString(t.label))
+	}
+	if x != nil {
+		for _, t := range xTicks {
+			fmt.Fprintf(b, `<line x1="%.1f" x2="%.1f" y1="%d" y2="%d" stroke="#ddd"/>`, x(t.value), x(t.value), marginTop, chartHeight-marginBottom)
+			fmt.Fprintf(b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`, x(t.value), chartHeight-marginBottom+16, html.EscapeString(t.label))
+		}
+	}
+	fmt.Fprintf(b, `<line x1="%d" x2="%d" y1="%d" y2="%d" stroke="#333"/>`, marginLeft, chartWidth-marginRight, chartHeight-marginBottom, chartHeight-marginBottom)
+	if xLabel != "" {
+		fmt.Fprintf(b, `<text x="%d" y="%d" text-anchor="middle">%s</text>`, (chartWidth+marginLeft)/2, chartHeight-8, html.EscapeString(xLabel))
+	}
+	if yLabel != "" {
+		fmt.Fprintf(b, `<text transform="translate(14 %d) rotate(-90)" text-anchor="middle">%s</text>`, (chartHeight+marginTop-marginBottom)/2, html.EscapeString(yLabel))
+	}
+}
+
+// legend lists the series below the chart.
+func legend(b *strings.Builder, series []series) {
+	for i, s := range series {
+		top := chartHeight + legendRow*i
+		fmt.Fprintf(b, `<rect x="%d" y="%d" width="12" height="12" fill="%s"/>`, marginLeft, top, color(i))
+		fmt.Fprintf(b, `<text x="%d" y="%d">%s</text>`, marginLeft+18, top+10, html.EscapeString(s.name))
+	}
+}
+
+func emptyChart(title string) template.HTML {
+	var b strings.Builder
+	openSVG(&b, 40, title)
+	fmt.Fprintf(&b, `<text x="%d" y="34" fill="#888">No data</text></svg>`, marginLeft)
+	return template.HTML(b.String())
+}
+
+// niceTicks returns about five round tick values covering [min, max].
+func niceTicks(min, max float64, format func(float64) string) []tick {
+	if max <= min {
+		max = min + 1
+	}
+	raw := (max - min) / 5
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := magnitude
+	for _, m := range []float64{1, 2, 5, 10} {
+		step = m * magnitude
+		if step >= raw {
+			break
+		}
+	}
+	var ticks []tick
+	first := math.Floor(min / step)
+	for i := 0.0; ; i++ {
+		// Multiplying rather than adding up steps avoids accumulating error.
+		v := (first + i) * step
+		ticks = append(ticks, tick{value: v, label: format(v)})
+		if v >= max {
+			return ticks
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// Arrival schedules supported by open-loop mode.
+const (
+	ArrivalFixed   = "fixed"
+	ArrivalPoisson = "poisson"
+)
+
+// interArrival returns a function producing the gap between two consecutive
+// intended start times at the given rate. Poisson gaps are drawn from rng.
+func interArrival(arrival string, rng *rand.Rand) (func(rate float64) time.Duration, error) {
+	switch arrival {
+	case ArrivalFixed, "":
+		return func(rate float64) time.Duration {
+			return time.Duration(float64(time.Second) / rate)
+		}, nil
+	case ArrivalPoisson:
+		return func(rate float64) time.Duration {
+			return time.Duration(rng.ExpFloat64() * float64(time.Second) / rate)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported arrival schedule: %s", arrival)
+	}
+}
+
+// schedule emits the intended start time of every operation in open-loop
+// mode. Each send blocks until a worker is free, but the times themselves
+// follow the schedule, so an operation that waited for a worker is charged
+// for the wait. The channel is closed at the deadline or when ctx is done;
+// operations that were due before the deadline but never started are counted
+// in backlog.
+func schedule(ctx context.Context, gap func(rate float64) time.Duration, rateAt func(time.Time) float64, start, deadline time.Time, backlog *int64) <-chan time.Time {
+	// advance returns the intended start time following t. While the rate
+	// is zero nothing is due, so it skips ahead until the rate picks up.
+	advance := func(t time.Time) time.Time {
+		if rate := rateAt(t); rate > 0 {
+			return t.Add(gap(rate))
+		}
+		return t.Add(profileTick)
+	}
+
+	ch := make(chan time.Time)
+	go func() {
+		defer close(ch)
+		deadlineTimer := time.NewTimer(time.Until(deadline))
+		defer deadlineTimer.Stop()
+
+		for next := start; next.Before(deadline); next = advance(next) {
+			if rateAt(next) <= 0 {
+				continue
+			}
+			if wait := time.Until(next); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				}
+			}
+			select {
+			case ch <- next:
+			case <-ctx.Done():
+				return
+			case <-deadlineTimer.C:
+				var missed int64
+				for ; next.Before(deadline); next = advance(next) {
+					if rateAt(next) > 0 {
+						missed++
+					}
+				}
+				atomic.AddInt64(backlog, missed)
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,136 @@
+package runner
+
+import (
+	"database-benchmark/internal/database"
+	"fmt"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Merge pools the results of repeated trials of one test into a single
+// result, as if their measured phases had run back to back. Latencies are
+// recomputed from the merged histograms and the intervals of every trial are
+// concatenated. The pooled histograms come first, followed by copies of the
+// trials' own, tagged "trial-N-TAG".
+func Merge(trials []*database.Result) *database.Result {
+	if len(trials) == 1 {
+		return trials[0]
+	}
+
+	merged := &database.Result{
+		TargetRate:    trials[0].TargetRate,
+		Seed:          trials[0].Seed,
+		DataIntegrity: true,
+		Verified:      true,
+	}
+	total := newCounts()
+	ops := make(map[string]*counts)
+	var stages []*counts
+	var userRate float64
+	var start, end int64
+	var trialHists []*hdrhistogram.Histogram
+	for i, trial := range trials {
+		// Map the trial's histograms by tag before retagging them.
+		hists := make(map[string]*hdrhistogram.Histogram)
+		for _, h := range trial.Histograms {
+			hists[h.Tag()] = h
+		}
+
+		if h := hists["total"]; h != nil {
+			if start == 0 {
+				start = h.StartTimeMs()
+			}
+			end = h.EndTimeMs()
+		}
+		mergeCounts(total, trial.Stats, hists["total"])
+		for name, s := range trial.ByOperation {
+			if ops[name] == nil {
+				ops[name] = newCounts()
+			}
+			mergeCounts(ops[name], s, hists["op-"+name])
+		}
+		for j, stage := range trial.Stages {
+			if j == len(merged.Stages) {
+				merged.Stages = append(merged.Stages, database.StageResult{Start: stage.Start, Target: stage.Target})
+				stages = append(stages, newCounts())
+			}
+			merged.Stages[j].Duration += stage.Duration
+			mergeCounts(stages[j], stage.Stats, hists[fmt.Sprintf("stage-%d", j)])
+		}
+
+		for _, interval := range trial.Intervals {
+			interval.Start += merged.TotalTime
+			merged.Intervals = append(merged.Intervals, interval)
+		}
+		for class, ec := range trial.ErrorClasses {
+			if merged.ErrorClasses == nil {
+				merged.ErrorClasses = make(map[string]*database.ErrorClass)
+			}
+			if merged.ErrorClasses[class] == nil {
+				merged.ErrorClasses[class] = &database.ErrorClass{}
+			}
+			merged.ErrorClasses[class].Merge(ec)
+		}
+		for class, n := range trial.RetriesByClass {
+			if merged.RetriesByClass == nil {
+				merged.RetriesByClass = make(map[string]int64)
+			}
+			merged.RetriesByClass[class] += n
+		}
+
+		merged.TotalTime += trial.TotalTime
+		merged.WarmupTime += trial.WarmupTime
+		merged.CooldownTime += trial.CooldownTime
+		merged.Backlog += trial.Backlog
+		merged.Retries += trial.Retries
+		merged.GaveUp += trial.GaveUp
+		merged.DataIntegrity = merged.DataIntegrity && trial.DataIntegrity
+		merged.Verified = merged.Verified && trial.Verified
+		if merged.VerifyError == "" {
+			merged.VerifyError = trial.VerifyError
+		}
+		merged.Interrupted = merged.Interrupted || trial.Interrupted
+		if trial.VirtualUsers > merged.VirtualUsers {
+			merged.VirtualUsers = trial.VirtualUsers
+		}
+		userRate += trial.UserRate
+
+		// The copies are retagged, leaving the trial's own histograms as
+		// they were.
+		for _, h := range trial.Histograms {
+			c := hdrhistogram.Import(h.Export())
+			trialHists = append(trialHists, stamp(c, fmt.Sprintf("trial-%d-%s", i+1, h.Tag()), time.UnixMilli(h.StartTimeMs()), time.UnixMilli(h.EndTimeMs())))
+		}
+	}
+
+	total.fill(&merged.Stats, merged.TotalTime)
+	rec := &recorder{total: total, ops: ops}
+	if len(ops) > 0 {
+		merged.ByOperation = rec.byOperation(merged.TotalTime)
+	}
+	if start != 0 {
+		from, to := time.UnixMilli(start), time.UnixMilli(end)
+		merged.Histograms = rec.histograms(from, to)
+		for j, c := range stages {
+			merged.Histograms = append(merged.Histograms, stamp(c.histogram, fmt.Sprintf("stage-%d", j), from, to))
+		}
+	}
+	merged.Histograms = append(merged.Histograms, trialHists...)
+	for j, c := range stages {
+		c.fill(&merged.Stages[j].Stats, merged.Stages[j].Duration)
+	}
+	merged.UserRate = userRate / float64(len(trials))
+	return merged
+}
+
+// mergeCounts adds the counts in s and the histogram h behind them to c.
+// Without a histogram, as for results read back from JSON, only the counts
+// are merged.
+func mergeCounts(c *counts, s database.Stats, h *hdrhistogram.Histogram) {
+	c.operations += s.Operations
+	c.errors += s.Errors
+	if h != nil {
+		c.histogram.Merge(h)
+	}
+}
@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"database-benchmark/internal/database"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// trial returns a result whose histograms carry tags, each holding values in
+// microseconds, over the second starting at start.
+func trial(start time.Time, tags []string, values ...int64) *database.Result {
+	result := &database.Result{
+		Stats:         database.Stats{Operations: int64(len(values))},
+		TotalTime:     time.Second,
+		Verified:      true,
+		DataIntegrity: true,
+		ByOperation:   make(map[string]database.Stats),
+	}
+	for _, tag := range tags {
+		h := hdrhistogram.New(1, MaxLatency, 3)
+		for _, v := range values {
+			h.RecordValue(v)
+		}
+		result.Histograms = append(result.Histograms, stamp(h, tag, start, start.Add(time.Second)))
+		if name, ok := strings.CutPrefix(tag, "op-"); ok {
+			result.ByOperation[name] = result.Stats
+		}
+	}
+	return result
+}
+
+func TestMergeLeavesTrialsUnchanged(t *testing.T) {
+	base := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name      string
+		trials    []*database.Result
+		wantTrial []string
+	}{
+		{
+			name:      "totals",
+			trials:    []*database.Result{trial(base, []string{"total"}, 100, 200), trial(base.Add(time.Second), []string{"total"}, 300)},
+			wantTrial: []string{"trial-1-total", "trial-2-total"},
+		},
+		{
+			name: "operations",
+			trials: []*database.Result{
+				trial(base, []string{"total", "op-read"}, 100),
+				trial(base.Add(time.Second), []string{"total", "op-read"}, 200),
+				trial(base.Add(2*time.Second), []string{"total", "op-read"}, 300),
+			},
+			wantTrial: []string{"trial-1-total", "trial-1-op-read", "trial-2-total", "trial-2-op-read", "trial-3-total", "trial-3-op-read"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			type snapshot struct {
+				tag        string
+				start, end int64
+				count      int64
+			}
+			var before []snapshot
+			for _, trial := range tt.trials {
+				for _, h := range trial.Histograms {
+					before = append(before, snapshot{h.Tag(), h.StartTimeMs(), h.EndTimeMs(), h.TotalCount()})
+				}
+			}
+
+			// Merging twice must give the same tags as merging once.
+			Merge(tt.trials)
+			merged := Merge(tt.trials)
+
+			var after []snapshot
+			for _, trial := range tt.trials {
+				for _, h := range trial.Histograms {
+					after = append(after, snapshot{h.Tag(), h.StartTimeMs(), h.EndTimeMs(), h.TotalCount()})
+				}
+			}
+			if !reflect.DeepEqual(after, before) {
+				t.Errorf("trial histograms changed: got %+v, want %+v", after, before)
+			}
+
+			var gotTrial []string
+			for _, h := range merged.Histograms {
+				if strings.HasPrefix(h.Tag(), "trial-") {
+					gotTrial = append(gotTrial, h.Tag())
+				}
+			}
+			if !reflect.DeepEqual(gotTrial, tt.wantTrial) {
+				t.Errorf("trial tags = %q, want %q", gotTrial, tt.wantTrial)
+			}
+			for _, trial := range tt.trials {
+				for _, h := range trial.Histograms {
+					for _, m := range merged.Histograms {
+						if m == h {
+							t.Errorf("merged result shares the histogram tagged %q with its trial", h.Tag())
+						}
+					}
+				}
+			}
+		})
+	}
+}
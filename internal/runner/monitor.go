@@ -0,0 +1,107 @@
+package runner
+
+import "time"
+
+// Phases of a run.
+const (
+	PhaseWarmup   = "warmup"
+	PhaseMeasure  = "measure"
+	PhaseCooldown = "cooldown"
+)
+
+// Phase returns the phase of a run measured from measureStart to measureEnd
+// at t.
+func Phase(t, measureStart, measureEnd time.Time) string {
+	switch {
+	case t.Before(measureStart):
+		return PhaseWarmup
+	case t.Before(measureEnd):
+		return PhaseMeasure
+	default:
+		return PhaseCooldown
+	}
+}
+
+// Monitor follows a run as it happens, for example to export live metrics.
+// Unlike the Result, it sees the operations of the warmup and cooldown too.
+// Its methods are called concurrently by the workers.
+type Monitor interface {
+	// Start is called when the workers start, with the bounds of the
+	// measured phase and the end of the run.
+	Start(measureStart, measureEnd, deadline time.Time)
+	// Begin is called when a worker starts an operation. The operation
+	// then either completes with Finish, after any retries, or is cut short
+	// by the end of the run and reported with Abandon.
+	Begin()
+	Finish(latency time.Duration, err error)
+	Abandon()
+	// Retry is called for every failed attempt of class that is retried.
+	Retry(class string)
+	// End is called once every worker has stopped.
+	End()
+}
+
+// Monitors returns a monitor that passes every call on to each of monitors
+// in turn, skipping nil ones, or nil if there are none.
+func Monitors(monitors ...Monitor) Monitor {
+	var multi multiMonitor
+	for _, m := range monitors {
+		if m != nil {
+			multi = append(multi, m)
+		}
+	}
+	switch len(multi) {
+	case 0:
+		return nil
+	case 1:
+		return multi[0]
+	}
+	return multi
+}
+
+type multiMonitor []Monitor
+
+func (multi multiMonitor) Start(measureStart, measureEnd, deadline time.Time) {
+	for _, m := range multi {
+		m.Start(measureStart, measureEnd, deadline)
+	}
+}
+
+func (multi multiMonitor) Begin() {
+	for _, m := range multi {
+		m.Begin()
+	}
+}
+
+func (multi multiMonitor) Finish(latency time.Duration, err error) {
+	for _, m := range multi {
+		m.Finish(latency, err)
+	}
+}
+
+func (multi multiMonitor) Abandon() {
+	for _, m := range multi {
+		m.Abandon()
+	}
+}
+
+func (multi multiMonitor) Retry(class string) {
+	for _, m := range multi {
+		m.Retry(class)
+	}
+}
+
+func (multi multiMonitor) End() {
+	for _, m := range multi {
+		m.End()
+	}
+}
+
+type nopMonitor struct{}
+
+func (nopMonitor) Start(measureStart, measureEnd, deadline time.Time) {}
+func (nopMonitor) Begin()                                             {}
+func (nopMonitor) Finish(latency time.Duration, err error)            {}
+func (nopMonitor) Abandon()                                           {}
+func (nopMonitor) Retry(class string)                                 {}
+func (nopMonitor) End()                                               {}
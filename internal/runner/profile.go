@@ -0,0 +1,140 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Built-in load profiles. Any other profile name is looked up in config.yaml.
+const (
+	ProfileConstant = "constant"
+	ProfileRamp     = "ramp"
+	ProfileStep     = "step"
+	ProfileSpike    = "spike"
+)
+
+// SpikeBase is the level the spike profile holds outside the spike, as a
+// fraction of its peak.
+const SpikeBase = 0.2
+
+// profileTick is how often idle workers and the open-loop scheduler
+// re-check the load level.
+const profileTick = 100 * time.Millisecond
+
+// Stage is one segment of a load profile. The load level is the number of
+// active workers in closed-loop mode and the arrival rate in open-loop mode.
+// When Ramp is set the level moves linearly from the previous stage's Target
+// to this Target over Duration; otherwise it jumps to Target and holds.
+type Stage struct {
+	Duration time.Duration
+	Target   float64
+	Ramp     bool
+}
+
+// BuildProfile returns the stages of a built-in profile that peaks at target
+// over the given duration. Ramp and step are split into steps stages.
+//
+//   - ramp: the level rises linearly from zero to target.
+//   - step: the level jumps by target/steps at the start of every stage.
+//   - spike: the level holds at SpikeBase of target, jumps to target for the
+//     middle fifth of the run, then recovers.
+func BuildProfile(name string, target float64, duration time.Duration, steps int) ([]Stage, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("profile steps must be positive, got %d", steps)
+	}
+	switch name {
+	case ProfileConstant, "":
+		return nil, nil
+	case ProfileRamp, ProfileStep:
+		stages := make([]Stage, steps)
+		for i := range stages {
+			stages[i] = Stage{
+				Duration: duration / time.Duration(steps),
+				Target:   target * float64(i+1) / float64(steps),
+				Ramp:     name == ProfileRamp,
+			}
+		}
+		return stages, nil
+	case ProfileSpike:
+		base := target * SpikeBase
+		return []Stage{
+			{Duration: duration * 2 / 5, Target: base},
+			{Duration: duration / 5, Target: target},
+			{Duration: duration * 2 / 5, Target: base},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported load profile: %s", name)
+	}
+}
+
+// profile answers which stage is running and at what level, given the time
+// elapsed since the start of the measured phase.
+type profile struct {
+	stages []Stage
+	ends   []time.Duration
+}
+
+func newProfile(stages []Stage) (*profile, error) {
+	if len(stages) == 0 {
+		return nil, nil
+	}
+	p := &profile{stages: stages}
+	var end time.Duration
+	for i, stage := range stages {
+		if stage.Duration <= 0 || stage.Target < 0 {
+			return nil, fmt.Errorf("invalid profile stage %d: duration %v, target %v", i, stage.Duration, stage.Target)
+		}
+		end += stage.Duration
+		p.ends = append(p.ends, end)
+	}
+	return p, nil
+}
+
+func (p *profile) duration() time.Duration {
+	return p.ends[len(p.ends)-1]
+}
+
+func (p *profile) peak() float64 {
+	var peak float64
+	for _, stage := range p.stages {
+		peak = math.Max(peak, stage.Target)
+	}
+	return peak
+}
+
+// stageAt returns the index of the stage running at elapsed, or -1 outside
+// the measured phase.
+func (p *profile) stageAt(elapsed time.Duration) int {
+	if elapsed < 0 {
+		return -1
+	}
+	for i, end := range p.ends {
+		if elapsed < end {
+			return i
+		}
+	}
+	return -1
+}
+
+// levelAt returns the load level at elapsed. Warmup holds the first stage's
+// target and cooldown holds the last one.
+func (p *profile) levelAt(elapsed time.Duration) float64 {
+	if elapsed < 0 {
+		return p.stages[0].Target
+	}
+	i := p.stageAt(elapsed)
+	if i < 0 {
+		return p.stages[len(p.stages)-1].Target
+	}
+	stage := p.stages[i]
+	if !stage.Ramp {
+		return stage.Target
+	}
+	var from float64
+	if i > 0 {
+		from = p.stages[i-1].Target
+	}
+	progress := float64(stage.Duration-(p.ends[i]-elapsed)) / float64(stage.Duration)
+	return from + (stage.Target-from)*progress
+}
@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuildProfile(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  string
+		target   float64
+		duration time.Duration
+		steps    int
+		want     []Stage
+		wantErr  bool
+	}{
+		{name: "constant", profile: ProfileConstant, target: 10, duration: time.Minute, steps: 5},
+		{name: "empty name is constant", profile: "", target: 10, duration: time.Minute, steps: 5},
+		{
+			name: "ramp", profile: ProfileRamp, target: 10, duration: 30 * time.Second, steps: 2,
+			want: []Stage{
+				{Duration: 15 * time.Second, Target: 5, Ramp: true},
+				{Duration: 15 * time.Second, Target: 10, Ramp: true},
+			},
+		},
+		{
+			name: "step", profile: ProfileStep, target: 9, duration: 30 * time.Second, steps: 3,
+			want: []Stage{
+				{Duration: 10 * time.Second, Target: 3},
+				{Duration: 10 * time.Second, Target: 6},
+				{Duration: 10 * time.Second, Target: 9},
+			},
+		},
+		{
+			name: "spike", profile: ProfileSpike, target: 50, duration: 50 * time.Second, steps: 5,
+			want: []Stage{
+				{Duration: 20 * time.Second, Target: 10},
+				{Duration: 10 * time.Second, Target: 50},
+				{Duration: 20 * time.Second, Target: 10},
+			},
+		},
+		{
+			name: "spike does not depend on steps", profile: ProfileSpike, target: 50, duration: 50 * time.Second, steps: 1,
+			want: []Stage{
+				{Duration: 20 * time.Second, Target: 10},
+				{Duration: 10 * time.Second, Target: 50},
+				{Duration: 20 * time.Second, Target: 10},
+			},
+		},
+		{name: "zero steps", profile: ProfileRamp, target: 10, duration: time.Minute, steps: 0, wantErr: true},
+		{name: "unknown profile", profile: "sawtooth", target: 10, duration: time.Minute, steps: 5, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildProfile(tt.profile, tt.target, tt.duration, tt.steps)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildProfile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewProfileRejectsInvalidStages(t *testing.T) {
+	tests := []struct {
+		name   string
+		stages []Stage
+	}{
+		{name: "zero duration", stages: []Stage{{Duration: 0, Target: 1}}},
+		{name: "negative target", stages: []Stage{{Duration: time.Second, Target: -1}}},
+		{name: "invalid later stage", stages: []Stage{{Duration: time.Second, Target: 1}, {Duration: -time.Second, Target: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newProfile(tt.stages); err == nil {
+				t.Errorf("newProfile(%+v) succeeded, want an error", tt.stages)
+			}
+		})
+	}
+}
+
+func TestProfileLevelAt(t *testing.T) {
+	p, err := newProfile([]Stage{
+		{Duration: 10 * time.Second, Target: 10, Ramp: true},
+		{Duration: 10 * time.Second, Target: 20},
+		{Duration: 10 * time.Second, Target: 0, Ramp: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.duration(); got != 30*time.Second {
+		t.Errorf("duration() = %v, want 30s", got)
+	}
+	if got := p.peak(); got != 20 {
+		t.Errorf("peak() = %v, want 20", got)
+	}
+
+	tests := []struct {
+		elapsed   time.Duration
+		wantStage int
+		wantLevel float64
+	}{
+		{elapsed: -time.Second, wantStage: -1, wantLevel: 10},
+		{elapsed: 0, wantStage: 0, wantLevel: 0},
+		{elapsed: 5 * time.Second, wantStage: 0, wantLevel: 5},
+		{elapsed: 10 * time.Second, wantStage: 1, wantLevel: 20},
+		{elapsed: 19 * time.Second, wantStage: 1, wantLevel: 20},
+		{elapsed: 25 * time.Second, wantStage: 2, wantLevel: 10},
+		{elapsed: 30 * time.Second, wantStage: -1, wantLevel: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.elapsed.String(), func(t *testing.T) {
+			if got := p.stageAt(tt.elapsed); got != tt.wantStage {
+				t.Errorf("stageAt(%v) = %d, want %d", tt.elapsed, got, tt.wantStage)
+			}
+			if got := p.levelAt(tt.elapsed); got != tt.wantLevel {
+				t.Errorf("levelAt(%v) = %v, want %v", tt.elapsed, got, tt.wantLevel)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package runner
+
+import (
+	"database-benchmark/internal/database"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Latencies are recorded in microseconds with three significant digits.
+// Longer operations are recorded as MaxLatency rather than dropped. Monitors
+// that keep their own histograms use the same bounds.
+const MaxLatency = int64(time.Hour / time.Microsecond)
+
+// LatencyMicros returns latency as a histogram value: microseconds, capped at
+// MaxLatency.
+func LatencyMicros(latency time.Duration) int64 {
+	return min(latency.Microseconds(), MaxLatency)
+}
+
+// counts is the outcome of a set of operations.
+type counts struct {
+	histogram  *hdrhistogram.Histogram
+	operations int64
+	errors     int64
+}
+
+func newCounts() *counts {
+	return &counts{histogram: hdrhistogram.New(1, MaxLatency, 3)}
+}
+
+func (c *counts) add(latency time.Duration, err error) {
+	if err != nil {
+		c.errors++
+		return
+	}
+	c.operations++
+	c.histogram.RecordValue(LatencyMicros(latency))
+}
+
+// fill copies the counts and latency percentiles into stats measured over
+// elapsed.
+func (c *counts) fill(result *database.Stats, elapsed time.Duration) {
+	result.Operations = c.operations
+	result.Errors = c.errors
+	if elapsed > 0 {
+		result.Throughput = float64(c.operations) / elapsed.Seconds()
+	}
+	if c.operations+c.errors > 0 {
+		result.ErrorRate = float64(c.errors) / float64(c.operations+c.errors)
+	}
+	h := c.histogram
+	result.MinLatency = micros(float64(h.Min()))
+	result.P50Latency = micros(float64(h.ValueAtQuantile(50)))
+	result.P90Latency = micros(float64(h.ValueAtQuantile(90)))
+	result.P95Latency = micros(float64(h.ValueAtQuantile(95)))
+	result.P99Latency = micros(float64(h.ValueAtQuantile(99)))
+	result.P999Latency = micros(float64(h.ValueAtQuantile(99.9)))
+	result.P9999Latency = micros(float64(h.ValueAtQuantile(99.99)))
+	result.MaxLatency = micros(float64(h.Max()))
+	result.AverageLatency = micros(h.Mean())
+	result.StdDevLatency = micros(h.StdDev())
+}
+
+// micros converts a histogram value to a duration.
+func micros(v float64) time.Duration {
+	return time.Duration(v * float64(time.Microsecond))
+}
+
+func (c *counts) reset() {
+	c.histogram.Reset()
+	c.operations = 0
+	c.errors = 0
+}
+
+// recorder collects the outcome of every measured operation across all
+// workers, overall, per load profile stage and per interval window.
+type recorder struct {
+	mu     sync.Mutex
+	total  *counts
+	stages []*counts
+	ops    map[string]*counts
+	// errorClasses classifies the errors counted in total.
+	errorClasses map[string]*database.ErrorClass
+	// retries counts failed attempts that were retried, retryClasses by
+	// error class, and gaveUp the operations that still failed after the
+	// last attempt.
+	retries      int64
+	retryClasses map[string]int64
+	gaveUp       int64
+
+	// window accumulates the interval that started at windowStart and spare
+	// takes over from it at the next rotation. measureStart anchors the
+	// interval offsets.
+	window       *counts
+	spare        *counts
+	windowStart  time.Time
+	measureStart time.Time
+	intervals    []database.IntervalResult
+}
+
+func newRecorder(stages int, measureStart time.Time) *recorder {
+	r := &recorder{
+		total:        newCounts(),
+		ops:          make(map[string]*counts),
+		errorClasses: make(map[string]*database.ErrorClass),
+		retryClasses: make(map[string]int64),
+		window:       newCounts(),
+		spare:        newCounts(),
+		windowStart:  measureStart,
+		measureStart: measureStart,
+	}
+	for i := 0; i < stages; i++ {
+		r.stages = append(r.stages, newCounts())
+	}
+	return r
+}
+
+// rotate closes the current interval window at end and opens the next one.
+// Only one goroutine rotates, so the closed window is summarized and its
+// histogram encoded without holding up the workers.
+func (r *recorder) rotate(end time.Time) {
+	r.mu.Lock()
+	window, start := r.window, r.windowStart
+	r.window, r.windowStart = r.spare, end
+	r.mu.Unlock()
+
+	interval := database.IntervalResult{
+		Start:    start.Sub(r.measureStart),
+		Duration: end.Sub(start),
+	}
+	window.fill(&interval.Stats, interval.Duration)
+	// Keeping the encoding rather than the histogram itself keeps the
+	// memory of long runs with short intervals small.
+	interval.Histogram, _ = window.histogram.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+	window.reset()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.intervals = append(r.intervals, interval)
+	r.spare = window
+}
+
+// record adds one operation. stage is -1 when no load profile is running
+// and name is empty when the workload did not name the operation.
+func (r *recorder) record(stage int, name string, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total.add(latency, err)
+	r.window.add(latency, err)
+	if stage >= 0 && stage < len(r.stages) {
+		r.stages[stage].add(latency, err)
+	}
+	if name != "" {
+		r.named(name).add(latency, err)
+	}
+	if err != nil {
+		class := database.ClassifyError(err)
+		if r.errorClasses[class] == nil {
+			r.errorClasses[class] = &database.ErrorClass{}
+		}
+		r.errorClasses[class].Add(err)
+	}
+}
+
+// recordRetry counts a failed attempt of class that is about to be retried.
+func (r *recorder) recordRetry(class string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retries++
+	r.retryClasses[class]++
+}
+
+// recordGiveUp counts an operation that failed on its last allowed attempt.
+func (r *recorder) recordGiveUp() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gaveUp++
+}
+
+// recordStep adds one step a workload timed with Worker.Track. Steps only
+// count towards the per-operation breakdown.
+func (r *recorder) recordStep(name string, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.named(name).add(latency, err)
+}
+
+func (r *recorder) named(name string) *counts {
+	c, ok := r.ops[name]
+	if !ok {
+		c = newCounts()
+		r.ops[name] = c
+	}
+	return c
+}
+
+// byOperation returns the per-operation breakdown measured over elapsed.
+func (r *recorder) byOperation(elapsed time.Duration) map[string]database.Stats {
+	if len(r.ops) == 0 {
+		return nil
+	}
+	stats := make(map[string]database.Stats, len(r.ops))
+	for name, c := range r.ops {
+		var s database.Stats
+		c.fill(&s, elapsed)
+		stats[name] = s
+	}
+	return stats
+}
+
+// histograms returns the overall and per-operation histograms, tagged and
+// stamped with the measured phase [start, end).
+func (r *recorder) histograms(start, end time.Time) []*hdrhistogram.Histogram {
+	hists := []*hdrhistogram.Histogram{stamp(r.total.histogram, "total", start, end)}
+	names := make([]string, 0, len(r.ops))
+	for name := range r.ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		hists = append(hists, stamp(r.ops[name].histogram, "op-"+name, start, end))
+	}
+	return hists
+}
+
+// stamp tags h and sets the period it covers.
+func stamp(h *hdrhistogram.Histogram, tag string, start, end time.Time) *hdrhistogram.Histogram {
+	h.SetTag(tag)
+	h.SetStartTimeMs(start.UnixMilli())
+	h.SetEndTimeMs(end.UnixMilli())
+	return h
+}
+
+// stepObserver forwards a worker's Track timings to the recorder while the
+// worker's current operation falls inside the measured phase.
+type stepObserver struct {
+	rec      *recorder
+	measured bool
+}
+
+func (o *stepObserver) ObserveOperation(name string, latency time.Duration, err error) {
+	if o.measured {
+		o.rec.recordStep(name, latency, err)
+	}
+}
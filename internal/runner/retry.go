@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"context"
+	"database-benchmark/internal/database"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy decides which failed operations the runner retries. An
+// operation is retried when its error falls in one of Classes, up to
+// MaxAttempts attempts in total, waiting an exponentially growing, jittered
+// backoff between attempts.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// Classes are database.ClassifyError classes worth retrying.
+	Classes []string
+}
+
+// DefaultRetryClasses are the transient errors a real application would
+// retry.
+var DefaultRetryClasses = []string{
+	database.ErrorSerialization,
+	database.ErrorDeadlock,
+	database.ErrorLockTimeout,
+	database.ErrorConnection,
+}
+
+// retryable returns the class of err and whether the policy retries it.
+func (p RetryPolicy) retryable(err error) (string, bool) {
+	class := database.ClassifyError(err)
+	for _, c := range p.Classes {
+		if c == class {
+			return class, true
+		}
+	}
+	return class, false
+}
+
+// delay returns the backoff before the attempt following attempt.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff << (attempt - 1)
+	if d <= 0 || (p.MaxBackoff > 0 && d > p.MaxBackoff) {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	// Half fixed, half random so that conflicting workers spread out. The
+	// jitter only shifts timing, so it does not draw from the run's seed.
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// sleep waits for d, returning false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
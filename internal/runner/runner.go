@@ -3,18 +3,432 @@ package runner
 import (
 	"context"
 	"database-benchmark/internal/database"
+	"errors"
+	"fmt"
 	"log"
+	"math"
+	"math/rand"
+	"sync"
 	"time"
 )
 
-func Run(ctx context.Context, db database.DatabaseDriver, workload database.Workload, concurrency int, duration time.Duration, logger *log.Logger) (*database.Result, error) {
+// Options controls how the runner drives a workload.
+type Options struct {
+	// Concurrency is the number of workers. In open-loop mode it caps the
+	// number of operations in flight.
+	Concurrency int
+	// Duration is the length of the measured phase.
+	Duration time.Duration
+	// Warmup and Cooldown run the workload before and after the measured
+	// phase. Operations started in either window execute normally but are
+	// left out of the Result.
+	Warmup   time.Duration
+	Cooldown time.Duration
+	// Rate is the target number of operations per second. Zero keeps the
+	// closed-loop behaviour where every worker issues its next operation as
+	// soon as the previous one returns.
+	Rate float64
+	// Arrival is the open-loop schedule: ArrivalFixed or ArrivalPoisson.
+	Arrival string
+	// ThinkTime is the mean pause a closed-loop worker takes after every
+	// operation, drawn from the Think distribution: ThinkFixed,
+	// ThinkUniform or ThinkExponential.
+	ThinkTime time.Duration
+	Think     string
+	// Pacing makes every closed-loop worker, or virtual user, start one
+	// iteration per Pacing instead of running them back to back. It cannot
+	// be combined with ThinkTime.
+	Pacing time.Duration
+	// Profile varies the load level over the measured phase, whose length
+	// then becomes the sum of the stage durations. The level is the number
+	// of active workers in closed-loop mode and the arrival rate in
+	// open-loop mode.
+	Profile []Stage
+	// Interval is the width of the windows in Result.Intervals. Zero
+	// disables the time series.
+	Interval time.Duration
+	// Seed seeds every worker's Worker.Rand and the Poisson schedule.
+	Seed int64
+	// Retry is applied to every failed operation.
+	Retry RetryPolicy
+	// OpTimeout bounds every operation attempt, so a stuck query fails with
+	// a context_timeout error instead of holding its worker. Zero disables
+	// it.
+	OpTimeout time.Duration
+	// Monitor, if set, follows the run live.
+	Monitor Monitor `json:"-"`
+}
+
+// arrivalStream is the database.NewRand stream of the open-loop schedule.
+const arrivalStream = -2
+
+// verifyTimeout bounds the integrity check that still runs after ctx has been
+// canceled.
+const verifyTimeout = time.Minute
+
+// Run drives the workload according to opts and assembles the Result. Every
+// worker calls workload.Operation in a loop until the deadline passes or the
+// workload reports database.ErrWorkloadDone. When ctx is canceled the workers
+// stop early and Run still returns the result collected so far, marked as
+// interrupted.
+func Run(ctx context.Context, db database.DatabaseDriver, workload database.Workload, opts Options, logger *log.Logger) (*database.Result, error) {
 	// Setup phase (if any) is handled by main.go
 
-	// Execute the workload's Run method
-	result, err := workload.Run(ctx, db, concurrency, duration, logger)
+	prof, err := newProfile(opts.Profile)
 	if err != nil {
 		return nil, err
 	}
 
+	if opts.Concurrency <= 0 {
+		return nil, fmt.Errorf("concurrency must be positive, got %d", opts.Concurrency)
+	}
+	if opts.Warmup < 0 || opts.Cooldown < 0 {
+		return nil, fmt.Errorf("warmup and cooldown must not be negative")
+	}
+	if opts.Interval < 0 {
+		return nil, fmt.Errorf("interval must not be negative, got %v", opts.Interval)
+	}
+	if opts.Rate < 0 {
+		return nil, fmt.Errorf("rate must not be negative, got %v", opts.Rate)
+	}
+	if opts.OpTimeout < 0 {
+		return nil, fmt.Errorf("operation timeout must not be negative, got %v", opts.OpTimeout)
+	}
+	if opts.ThinkTime < 0 || opts.Pacing < 0 {
+		return nil, fmt.Errorf("think time and pacing must not be negative")
+	}
+	if opts.ThinkTime > 0 && opts.Pacing > 0 {
+		return nil, fmt.Errorf("think time and pacing cannot be combined")
+	}
+	if (opts.ThinkTime > 0 || opts.Pacing > 0) && opts.Rate > 0 {
+		return nil, fmt.Errorf("think time and pacing only apply to closed-loop runs")
+	}
+	var think func(rng *rand.Rand) time.Duration
+	if opts.ThinkTime > 0 {
+		if think, err = thinkTime(opts.Think, opts.ThinkTime); err != nil {
+			return nil, err
+		}
+	}
+	openLoop := opts.Rate > 0
+	var gap func(rate float64) time.Duration
+	if openLoop {
+		if gap, err = interArrival(opts.Arrival, database.NewRand(opts.Seed, arrivalStream)); err != nil {
+			return nil, err
+		}
+	}
+
+	duration := opts.Duration
+	concurrency := opts.Concurrency
+	if prof != nil {
+		duration = prof.duration()
+		if !openLoop {
+			concurrency = int(math.Ceil(prof.peak()))
+		}
+	}
+
+	workers := make([]*database.Worker, concurrency)
+	for i := range workers {
+		workers[i] = &database.Worker{ID: i, Logger: logger, Rand: database.NewRand(opts.Seed, i)}
+		if initializer, ok := workload.(database.WorkerInitializer); ok {
+			if err := initializer.InitWorker(ctx, db, workers[i]); err != nil {
+				return nil, fmt.Errorf("failed to initialize worker %d: %w", i, err)
+			}
+		}
+	}
+
+	// runCtx is canceled as soon as one worker finds the workload exhausted
+	// or ctx is canceled.
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var stages int
+	if prof != nil {
+		stages = len(prof.stages)
+	}
+	startTime := time.Now()
+	measureStart := startTime.Add(opts.Warmup)
+	measureEnd := measureStart.Add(duration)
+	deadline := measureEnd.Add(opts.Cooldown)
+	rec := newRecorder(stages, measureStart)
+	monitor := opts.Monitor
+	if monitor == nil {
+		monitor = nopMonitor{}
+	}
+	monitor.Start(measureStart, measureEnd, deadline)
+
+	intervalsDone := make(chan struct{})
+	var intervalWg sync.WaitGroup
+	if opts.Interval > 0 {
+		intervalWg.Add(1)
+		go func() {
+			defer intervalWg.Done()
+			rotateIntervals(rec, opts.Interval, measureStart, measureEnd, intervalsDone)
+		}()
+	}
+
+	// level returns the load level at t, or the level of a constant run
+	// when there is no profile.
+	level := func(t time.Time) float64 {
+		if prof != nil {
+			return prof.levelAt(t.Sub(measureStart))
+		}
+		if openLoop {
+			return opts.Rate
+		}
+		return float64(concurrency)
+	}
+
+	var backlog int64
+	var intended <-chan time.Time
+	if openLoop {
+		intended = schedule(runCtx, gap, level, startTime, deadline, &backlog)
+	}
+
+	// iterations counts the measured operations of every worker for the
+	// per-user rate.
+	iterations := make([]int64, len(workers))
+
+	var wg sync.WaitGroup
+	for _, worker := range workers {
+		wg.Add(1)
+		go func(worker *database.Worker) {
+			defer wg.Done()
+			observer := &stepObserver{rec: rec}
+			worker.Observer = observer
+			var thinkRand *rand.Rand
+			if think != nil || opts.Pacing > 0 {
+				thinkRand = database.NewRand(opts.Seed, thinkStream(worker.ID))
+			}
+			// next is the planned start of a paced worker's next
+			// iteration. The first ones are spread over one pacing
+			// interval so the virtual users do not start in lockstep.
+			var next time.Time
+			if opts.Pacing > 0 {
+				next = startTime.Add(time.Duration(thinkRand.Int63n(int64(opts.Pacing))))
+			}
+			for {
+				// In open-loop mode latency is measured from the intended
+				// start time, which corrects for coordinated omission.
+				var opStartTime time.Time
+				if openLoop {
+					var ok bool
+					if opStartTime, ok = <-intended; !ok {
+						return
+					}
+				} else {
+					if opts.Pacing > 0 && !pause(runCtx, time.Until(next), deadline) {
+						return
+					}
+					if !waitForTurn(runCtx, worker.ID, level, deadline) {
+						return
+					}
+					opStartTime = time.Now()
+					if opts.Pacing > 0 {
+						next = pace(next, opStartTime, opts.Pacing)
+					}
+				}
+
+				measured := !opStartTime.Before(measureStart) && opStartTime.Before(measureEnd)
+				observer.measured = measured
+
+				var err error
+				var gaveUp bool
+				monitor.Begin()
+				for attempt := 1; ; attempt++ {
+					worker.Op = ""
+					err = operation(runCtx, db, workload, worker, opts.OpTimeout)
+					if err == nil || errors.Is(err, database.ErrWorkloadDone) {
+						break
+					}
+					class, ok := opts.Retry.retryable(err)
+					if !ok {
+						break
+					}
+					if attempt >= opts.Retry.MaxAttempts {
+						gaveUp = attempt > 1
+						break
+					}
+					monitor.Retry(class)
+					if measured {
+						rec.recordRetry(class)
+					}
+					if !sleep(runCtx, opts.Retry.delay(attempt)) {
+						break
+					}
+				}
+				if errors.Is(err, database.ErrWorkloadDone) {
+					monitor.Abandon()
+					cancel()
+					return
+				}
+				if err != nil && runCtx.Err() != nil {
+					// Interrupted by the run stopping, not by the operation
+					monitor.Abandon()
+					return
+				}
+				latency := time.Since(opStartTime)
+				monitor.Finish(latency, err)
+				if measured {
+					stage := -1
+					if prof != nil {
+						stage = prof.stageAt(opStartTime.Sub(measureStart))
+					}
+					rec.record(stage, worker.Op, latency, err)
+					if gaveUp {
+						rec.recordGiveUp()
+					}
+					iterations[worker.ID]++
+				}
+				worker.Iteration++
+				if think != nil && !pause(runCtx, think(thinkRand), deadline) {
+					return
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+	stopTime := time.Now()
+	monitor.End()
+	close(intervalsDone)
+	intervalWg.Wait()
+	if opts.Interval > 0 && stopTime.After(rec.windowStart) {
+		// The last window also takes operations that completed after the
+		// measured phase ended.
+		end := measureEnd
+		if stopTime.Before(end) {
+			end = stopTime
+		}
+		rec.rotate(end)
+	}
+
+	result := &database.Result{
+		WarmupTime:   phaseLength(startTime, measureStart, stopTime),
+		TotalTime:    phaseLength(measureStart, measureEnd, stopTime),
+		CooldownTime: phaseLength(measureEnd, deadline, stopTime),
+		TargetRate:   opts.Rate,
+		Backlog:      backlog,
+		Intervals:    rec.intervals,
+		Seed:         opts.Seed,
+		Interrupted:  ctx.Err() != nil,
+	}
+	rec.total.fill(&result.Stats, result.TotalTime)
+	result.ByOperation = rec.byOperation(result.TotalTime)
+	result.Histograms = rec.histograms(measureStart, measureStart.Add(result.TotalTime))
+	if len(rec.errorClasses) > 0 {
+		result.ErrorClasses = rec.errorClasses
+	}
+	if !openLoop {
+		result.VirtualUsers, result.UserRate = userRate(iterations, result.TotalTime)
+	}
+	result.Retries = rec.retries
+	result.GaveUp = rec.gaveUp
+	if len(rec.retryClasses) > 0 {
+		result.RetriesByClass = rec.retryClasses
+	}
+	for i, c := range rec.stages {
+		stageStart := measureStart.Add(prof.ends[i] - prof.stages[i].Duration)
+		stage := database.StageResult{
+			Start:    stageStart.Sub(measureStart),
+			Duration: phaseLength(stageStart, stageStart.Add(prof.stages[i].Duration), stopTime),
+			Target:   prof.stages[i].Target,
+		}
+		c.fill(&stage.Stats, stage.Duration)
+		result.Stages = append(result.Stages, stage)
+		result.Histograms = append(result.Histograms, stamp(c.histogram, fmt.Sprintf("stage-%d", i), stageStart, stageStart.Add(stage.Duration)))
+	}
+
+	if verifier, ok := workload.(database.Verifier); ok {
+		// Verification also runs after an interrupt, since a partial run
+		// must still leave the data consistent.
+		verifyCtx, cancelVerify := context.WithTimeout(context.WithoutCancel(ctx), verifyTimeout)
+		defer cancelVerify()
+		// A check that could not complete leaves the measured result
+		// standing, unverified.
+		ok, err := verifier.Verify(verifyCtx, db, logger)
+		if err != nil {
+			logger.Printf("Failed to verify data integrity: %v", err)
+			result.VerifyError = err.Error()
+		} else {
+			result.DataIntegrity = ok
+			result.Verified = true
+		}
+	}
+
 	return result, nil
-}
\ No newline at end of file
+}
+
+// operation runs a single attempt of the workload's operation, bounded by
+// timeout when it is positive.
+func operation(ctx context.Context, db database.DatabaseDriver, workload database.Workload, worker *database.Worker, timeout time.Duration) error {
+	if timeout <= 0 {
+		return workload.Operation(ctx, db, worker)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return workload.Operation(ctx, db, worker)
+}
+
+// userRate returns how many workers ran measured operations and the mean rate
+// at which each of them did over elapsed.
+func userRate(iterations []int64, elapsed time.Duration) (int, float64) {
+	var users int
+	var total int64
+	for _, n := range iterations {
+		if n > 0 {
+			users++
+			total += n
+		}
+	}
+	if users == 0 || elapsed <= 0 {
+		return users, 0
+	}
+	return users, float64(total) / float64(users) / elapsed.Seconds()
+}
+
+// rotateIntervals closes an interval window every interval from measureStart
+// until measureEnd or until done is closed. The final window is closed by the
+// caller once all workers have stopped.
+func rotateIntervals(rec *recorder, interval time.Duration, measureStart, measureEnd time.Time, done <-chan struct{}) {
+	for next := measureStart.Add(interval); next.Before(measureEnd); next = next.Add(interval) {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			rec.rotate(next)
+		case <-done:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// waitForTurn blocks a closed-loop worker while the load level leaves it
+// idle. It returns false once the run is over.
+func waitForTurn(ctx context.Context, id int, level func(time.Time) float64, deadline time.Time) bool {
+	for {
+		now := time.Now()
+		if !now.Before(deadline) || ctx.Err() != nil {
+			return false
+		}
+		// At least one worker stays active so a ramp from zero makes progress.
+		if float64(id) < math.Max(1, math.Round(level(now))) {
+			return true
+		}
+		select {
+		case <-time.After(profileTick):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// phaseLength returns how much of the phase [from, to) elapsed before the run
+// stopped, which is less than planned when the workload finished early.
+func phaseLength(from, to, stop time.Time) time.Duration {
+	if stop.Before(to) {
+		to = stop
+	}
+	if to.Before(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"context"
+	"database-benchmark/internal/database"
+	"fmt"
+	"time"
+)
+
+// SearchOptions bound the search for the maximum sustainable rate.
+type SearchOptions struct {
+	// MinRate is the first rate probed. The rate doubles from there until a
+	// probe fails or MaxRate is reached.
+	MinRate float64
+	// MaxRate caps the search; zero leaves it unbounded.
+	MaxRate float64
+	// MaxP99 is the p99 latency a probe must stay under and MaxErrorRate
+	// the error rate it may reach at most, so that zero allows no errors.
+	MaxP99       time.Duration
+	MaxErrorRate float64
+	// Precision stops the bisection once the gap between the highest
+	// passing and the lowest failing rate is within this fraction of the
+	// passing rate.
+	Precision float64
+	// MaxProbes caps the total number of probes.
+	MaxProbes int
+}
+
+// Probe is one run of a rate search.
+type Probe struct {
+	Rate   float64
+	Passed bool
+	Result *database.Result
+}
+
+// SearchResult is the outcome of a rate search. Rate is the highest probed
+// rate that met the service level, or zero if none did.
+type SearchResult struct {
+	Rate   float64
+	Probes []Probe
+}
+
+// Search looks for the highest open-loop rate at which run still meets the
+// service level in opts. run executes one probe at the given rate; each
+// probe is expected to start from a clean database.
+func Search(ctx context.Context, opts SearchOptions, run func(ctx context.Context, rate float64) (*database.Result, error)) (*SearchResult, error) {
+	if opts.MinRate <= 0 {
+		return nil, fmt.Errorf("minimum rate must be positive, got %v", opts.MinRate)
+	}
+	if opts.MaxRate != 0 && opts.MaxRate < opts.MinRate {
+		return nil, fmt.Errorf("maximum rate %v is below minimum rate %v", opts.MaxRate, opts.MinRate)
+	}
+	if opts.MaxProbes <= 0 {
+		return nil, fmt.Errorf("maximum probes must be positive, got %d", opts.MaxProbes)
+	}
+
+	search := &SearchResult{}
+	probe := func(rate float64) (bool, error) {
+		result, err := run(ctx, rate)
+		if err != nil {
+			return false, fmt.Errorf("probe at %.1f ops/s: %w", rate, err)
+		}
+		if result.Interrupted {
+			// A partial probe says nothing about the rate
+			return false, ctx.Err()
+		}
+		// A probe that completed nothing has no latency to judge, and the
+		// latency bound is one to stay under, not to reach.
+		passed := result.Operations > 0 && result.P99Latency < opts.MaxP99 && result.ErrorRate <= opts.MaxErrorRate
+		search.Probes = append(search.Probes, Probe{Rate: rate, Passed: passed, Result: result})
+		return passed, nil
+	}
+
+	// Grow the rate until a probe fails to find an upper bound.
+	var passing, failing float64
+	for rate := opts.MinRate; len(search.Probes) < opts.MaxProbes; rate *= 2 {
+		if opts.MaxRate != 0 && rate > opts.MaxRate {
+			rate = opts.MaxRate
+		}
+		passed, err := probe(rate)
+		if err != nil {
+			return search, err
+		}
+		if !passed {
+			failing = rate
+			break
+		}
+		passing = rate
+		if rate == opts.MaxRate {
+			break
+		}
+	}
+
+	// Bisect between the highest passing and the lowest failing rate.
+	for passing > 0 && failing > 0 && len(search.Probes) < opts.MaxProbes {
+		if failing-passing <= passing*opts.Precision {
+			break
+		}
+		rate := (passing + failing) / 2
+		passed, err := probe(rate)
+		if err != nil {
+			return search, err
+		}
+		if passed {
+			passing = rate
+		} else {
+			failing = rate
+		}
+	}
+
+	search.Rate = passing
+	return search, nil
+}
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"context"
+	"database-benchmark/internal/database"
+	"testing"
+	"time"
+)
+
+func TestSearchProbeVerdict(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxErrorRate float64
+		result       database.Result
+		want         bool
+	}{
+		{
+			name:         "under the service level",
+			maxErrorRate: 0.01,
+			result:       database.Result{Stats: database.Stats{Operations: 100, P99Latency: 10 * time.Millisecond, ErrorRate: 0.001}},
+			want:         true,
+		},
+		{
+			name:         "no operations",
+			maxErrorRate: 0.01,
+			result:       database.Result{},
+			want:         false,
+		},
+		{
+			name:         "p99 at the limit",
+			maxErrorRate: 0.01,
+			result:       database.Result{Stats: database.Stats{Operations: 100, P99Latency: 50 * time.Millisecond}},
+			want:         false,
+		},
+		{
+			name:         "error rate at the limit",
+			maxErrorRate: 0.01,
+			result:       database.Result{Stats: database.Stats{Operations: 100, P99Latency: 10 * time.Millisecond, ErrorRate: 0.01}},
+			want:         true,
+		},
+		{
+			name:         "error rate over the limit",
+			maxErrorRate: 0.01,
+			result:       database.Result{Stats: database.Stats{Operations: 100, P99Latency: 10 * time.Millisecond, ErrorRate: 0.02}},
+			want:         false,
+		},
+		{
+			name:         "no errors allowed",
+			maxErrorRate: 0,
+			result:       database.Result{Stats: database.Stats{Operations: 100, P99Latency: 10 * time.Millisecond}},
+			want:         true,
+		},
+		{
+			name:         "one error with none allowed",
+			maxErrorRate: 0,
+			result:       database.Result{Stats: database.Stats{Operations: 100, Errors: 1, P99Latency: 10 * time.Millisecond, ErrorRate: 0.0099}},
+			want:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := SearchOptions{MinRate: 10, MaxRate: 10, MaxP99: 50 * time.Millisecond, MaxErrorRate: tt.maxErrorRate, Precision: 0.1, MaxProbes: 1}
+			search, err := Search(context.Background(), opts, func(ctx context.Context, rate float64) (*database.Result, error) {
+				result := tt.result
+				return &result, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(search.Probes) != 1 {
+				t.Fatalf("got %d probes, want 1", len(search.Probes))
+			}
+			if got := search.Probes[0].Passed; got != tt.want {
+				t.Errorf("probe passed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchFindsRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     SearchOptions
+		capacity float64
+		want     float64
+	}{
+		{
+			name:     "bisects below the first failure",
+			opts:     SearchOptions{MinRate: 100, MaxP99: 50 * time.Millisecond, MaxErrorRate: 0.01, Precision: 0.05, MaxProbes: 20},
+			capacity: 500,
+			want:     500,
+		},
+		{
+			name:     "stops at the maximum rate",
+			opts:     SearchOptions{MinRate: 100, MaxRate: 300, MaxP99: 50 * time.Millisecond, MaxErrorRate: 0.01, Precision: 0.05, MaxProbes: 20},
+			capacity: 1000,
+			want:     300,
+		},
+		{
+			name:     "nothing passes",
+			opts:     SearchOptions{MinRate: 100, MaxP99: 50 * time.Millisecond, MaxErrorRate: 0.01, Precision: 0.05, MaxProbes: 20},
+			capacity: 50,
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search, err := Search(context.Background(), tt.opts, func(ctx context.Context, rate float64) (*database.Result, error) {
+				p99 := 10 * time.Millisecond
+				if rate > tt.capacity {
+					p99 = time.Second
+				}
+				return &database.Result{Stats: database.Stats{Operations: 100, P99Latency: p99}}, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if search.Rate > tt.want || search.Rate < tt.want*(1-tt.opts.Precision) {
+				t.Errorf("Rate = %v, want within %v of %v", search.Rate, tt.opts.Precision, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchRejectsInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts SearchOptions
+	}{
+		{name: "zero minimum rate", opts: SearchOptions{MaxProbes: 1}},
+		{name: "maximum below minimum", opts: SearchOptions{MinRate: 10, MaxRate: 5, MaxProbes: 1}},
+		{name: "zero probes", opts: SearchOptions{MinRate: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Search(context.Background(), tt.opts, func(ctx context.Context, rate float64) (*database.Result, error) {
+				t.Fatal("probe ran with invalid options")
+				return nil, nil
+			})
+			if err == nil {
+				t.Error("Search() succeeded, want an error")
+			}
+		})
+	}
+}
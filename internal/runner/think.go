@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Think time distributions for closed-loop workers.
+const (
+	ThinkFixed       = "fixed"
+	ThinkUniform     = "uniform"
+	ThinkExponential = "exponential"
+)
+
+// thinkTime returns a function drawing the pause a worker takes after each
+// operation. Every distribution has the given mean; uniform pauses fall
+// between zero and twice the mean.
+func thinkTime(dist string, mean time.Duration) (func(rng *rand.Rand) time.Duration, error) {
+	switch dist {
+	case ThinkFixed, "":
+		return func(*rand.Rand) time.Duration {
+			return mean
+		}, nil
+	case ThinkUniform:
+		return func(rng *rand.Rand) time.Duration {
+			return time.Duration(rng.Int63n(int64(2*mean) + 1))
+		}, nil
+	case ThinkExponential:
+		return func(rng *rand.Rand) time.Duration {
+			return time.Duration(rng.ExpFloat64() * float64(mean))
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported think time distribution: %s", dist)
+	}
+}
+
+// thinkStream is the database.NewRand stream of a worker's think times and
+// pacing offset, kept apart from Worker.Rand so that pauses do not shift the worker's data.
+func thinkStream(id int) int {
+	return arrivalStream - 1 - id
+}
+
+// pace returns when a paced worker should start its next iteration: one
+// pacing interval after the planned start of the current one, or after its
+// actual start when the worker fell a whole interval behind, so that it never
+// bursts to catch up.
+func pace(planned, started time.Time, pacing time.Duration) time.Time {
+	next := planned.Add(pacing)
+	if !next.After(started) {
+		next = started.Add(pacing)
+	}
+	return next
+}
+
+// pause waits for d, cut short at the deadline. It returns false if ctx is
+// done first.
+func pause(ctx context.Context, d time.Duration, deadline time.Time) bool {
+	if remaining := time.Until(deadline); d > remaining {
+		d = remaining
+	}
+	return sleep(ctx, d)
+}
@@ -0,0 +1,131 @@
+// Package slo checks results against the acceptance criteria declared in the
+// config.
+package slo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"database-benchmark/internal/config"
+	"database-benchmark/internal/database"
+)
+
+// Check is the outcome of one criterion, named after its config key.
+type Check struct {
+	Name   string
+	Want   string
+	Got    string
+	Passed bool
+}
+
+// Verdict is the outcome of every criterion of a test for one run.
+type Verdict struct {
+	DB       string
+	Workload string
+	Test     string
+	Checks   []Check
+	// Elapsed is the length of the measured phase.
+	Elapsed time.Duration
+}
+
+// Name identifies the run as db/workload/test.
+func (v Verdict) Name() string {
+	return v.DB + "/" + v.Workload + "/" + v.Test
+}
+
+// Passed reports whether every criterion was met.
+func (v Verdict) Passed() bool {
+	for _, check := range v.Checks {
+		if !check.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Evaluate checks result, a run of workload and test on db, against criteria.
+func Evaluate(db, workload, test string, criteria config.SLO, result *database.Result) Verdict {
+	v := Verdict{DB: db, Workload: workload, Test: test, Elapsed: result.TotalTime}
+	add := func(name, want, got string, passed bool) {
+		v.Checks = append(v.Checks, Check{Name: name, Want: want, Got: got, Passed: passed})
+	}
+
+	if criteria.MinThroughput > 0 {
+		add("min_throughput", fmt.Sprintf(">= %.1f ops/s", criteria.MinThroughput), fmt.Sprintf("%.1f ops/s", result.Throughput),
+			result.Throughput >= criteria.MinThroughput)
+	}
+	latency := func(name string, max, got time.Duration) {
+		if max > 0 {
+			add(name, fmt.Sprintf("<= %v", max), got.String(), got <= max)
+		}
+	}
+	latency("max_p50", criteria.MaxP50, result.P50Latency)
+	latency("max_p99", criteria.MaxP99, result.P99Latency)
+	latency("max_p999", criteria.MaxP999, result.P999Latency)
+	if criteria.MaxErrorRate != nil {
+		add("max_error_rate", fmt.Sprintf("<= %.2f%%", *criteria.MaxErrorRate*100), fmt.Sprintf("%.2f%%", result.ErrorRate*100),
+			result.ErrorRate <= *criteria.MaxErrorRate)
+	}
+	if criteria.RequireIntegrity {
+		add("require_integrity", "ok", result.Integrity(), result.Verified && result.DataIntegrity)
+	}
+	return v
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes verdicts as JUnit XML for CI systems: a test suite per
+// run and a test case per criterion.
+func WriteJUnit(w io.Writer, verdicts []Verdict) error {
+	var doc junitSuites
+	for _, v := range verdicts {
+		suite := junitSuite{Name: v.Name(), Time: fmt.Sprintf("%.3f", v.Elapsed.Seconds())}
+		for _, check := range v.Checks {
+			c := junitCase{Name: check.Name, ClassName: v.DB + "." + v.Workload + "." + v.Test}
+			if !check.Passed {
+				c.Failure = &junitFailure{Message: fmt.Sprintf("want %s, got %s", check.Want, check.Got)}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+		suite.Tests = len(suite.Cases)
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Suites = append(doc.Suites, suite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
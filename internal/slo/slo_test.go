@@ -0,0 +1,142 @@
+package slo
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"testing"
+	"time"
+
+	"database-benchmark/internal/config"
+	"database-benchmark/internal/database"
+)
+
+func TestEvaluate(t *testing.T) {
+	zero, onePercent := 0.0, 0.01
+	result := &database.Result{
+		Stats: database.Stats{
+			Throughput:  1000,
+			P50Latency:  2 * time.Millisecond,
+			P99Latency:  20 * time.Millisecond,
+			P999Latency: 50 * time.Millisecond,
+			ErrorRate:   0.005,
+		},
+		TotalTime:     30 * time.Second,
+		Verified:      true,
+		DataIntegrity: true,
+	}
+	tests := []struct {
+		name     string
+		criteria config.SLO
+		result   *database.Result
+		want     []Check
+	}{
+		{name: "no criteria", criteria: config.SLO{}, result: result},
+		{
+			name:     "all met",
+			criteria: config.SLO{MinThroughput: 500, MaxP50: 5 * time.Millisecond, MaxP99: 20 * time.Millisecond, MaxErrorRate: &onePercent, RequireIntegrity: true},
+			result:   result,
+			want: []Check{
+				{Name: "min_throughput", Want: ">= 500.0 ops/s", Got: "1000.0 ops/s", Passed: true},
+				{Name: "max_p50", Want: "<= 5ms", Got: "2ms", Passed: true},
+				{Name: "max_p99", Want: "<= 20ms", Got: "20ms", Passed: true},
+				{Name: "max_error_rate", Want: "<= 1.00%", Got: "0.50%", Passed: true},
+				{Name: "require_integrity", Want: "ok", Got: "ok", Passed: true},
+			},
+		},
+		{
+			name:     "missed",
+			criteria: config.SLO{MinThroughput: 2000, MaxP999: 10 * time.Millisecond, MaxErrorRate: &zero},
+			result:   result,
+			want: []Check{
+				{Name: "min_throughput", Want: ">= 2000.0 ops/s", Got: "1000.0 ops/s", Passed: false},
+				{Name: "max_p999", Want: "<= 10ms", Got: "50ms", Passed: false},
+				{Name: "max_error_rate", Want: "<= 0.00%", Got: "0.50%", Passed: false},
+			},
+		},
+		{
+			name:     "integrity failed",
+			criteria: config.SLO{RequireIntegrity: true},
+			result:   &database.Result{Verified: true},
+			want:     []Check{{Name: "require_integrity", Want: "ok", Got: "FAILED", Passed: false}},
+		},
+		{
+			name:     "integrity not checked",
+			criteria: config.SLO{RequireIntegrity: true},
+			result:   &database.Result{},
+			want:     []Check{{Name: "require_integrity", Want: "ok", Got: "-", Passed: false}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := Evaluate("postgres", "ecommerce", "order_processing", tt.criteria, tt.result)
+			if !reflect.DeepEqual(v.Checks, tt.want) {
+				t.Errorf("Checks = %+v, want %+v", v.Checks, tt.want)
+			}
+			wantPassed := true
+			for _, check := range tt.want {
+				wantPassed = wantPassed && check.Passed
+			}
+			if v.Passed() != wantPassed {
+				t.Errorf("Passed() = %v, want %v", v.Passed(), wantPassed)
+			}
+			if v.Name() != "postgres/ecommerce/order_processing" {
+				t.Errorf("Name() = %q", v.Name())
+			}
+		})
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	tests := []struct {
+		name         string
+		verdicts     []Verdict
+		wantTests    int
+		wantFailures int
+	}{
+		{name: "no verdicts"},
+		{
+			name: "passed and failed",
+			verdicts: []Verdict{
+				{DB: "postgres", Workload: "ecommerce", Test: "order_processing", Elapsed: 30 * time.Second, Checks: []Check{
+					{Name: "max_p99", Want: "<= 20ms", Got: "10ms", Passed: true},
+					{Name: "min_throughput", Want: ">= 500.0 ops/s", Got: "100.0 ops/s"},
+				}},
+				{DB: "mysql", Workload: "ecommerce", Test: "order_processing", Elapsed: 30 * time.Second, Checks: []Check{
+					{Name: "max_p99", Want: "<= 20ms", Got: "30ms"},
+				}},
+			},
+			wantTests:    3,
+			wantFailures: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteJUnit(&buf, tt.verdicts); err != nil {
+				t.Fatal(err)
+			}
+			var doc junitSuites
+			if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+				t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+			}
+			if doc.Tests != tt.wantTests || doc.Failures != tt.wantFailures {
+				t.Errorf("got %d tests, %d failures, want %d, %d", doc.Tests, doc.Failures, tt.wantTests, tt.wantFailures)
+			}
+			if len(doc.Suites) != len(tt.verdicts) {
+				t.Fatalf("got %d suites, want %d", len(doc.Suites), len(tt.verdicts))
+			}
+			for i, suite := range doc.Suites {
+				if suite.Name != tt.verdicts[i].Name() || suite.Time != "30.000" {
+					t.Errorf("suite %d = %s in %s, want %s in 30.000", i, suite.Name, suite.Time, tt.verdicts[i].Name())
+				}
+				for j, c := range suite.Cases {
+					check := tt.verdicts[i].Checks[j]
+					if (c.Failure == nil) != check.Passed {
+						t.Errorf("%s/%s failure = %+v, want passed %v", suite.Name, c.Name, c.Failure, check.Passed)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Package stats summarizes repeated measurements and tests whether two sets
+// of them differ.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Summary describes a set of samples. CILow and CIHigh bound the 95%
+// confidence interval of the mean.
+type Summary struct {
+	N      int
+	Mean   float64
+	StdDev float64
+	// CV is the coefficient of variation, StdDev relative to Mean.
+	CV     float64
+	CILow  float64
+	CIHigh float64
+}
+
+// Summarize returns the summary of samples. The standard deviation is the
+// sample standard deviation and the confidence interval uses Student's t
+// distribution, so both need at least two samples.
+func Summarize(samples []float64) Summary {
+	s := Summary{N: len(samples)}
+	if s.N == 0 {
+		return s
+	}
+	for _, v := range samples {
+		s.Mean += v
+	}
+	s.Mean /= float64(s.N)
+	s.CILow, s.CIHigh = s.Mean, s.Mean
+	if s.N < 2 {
+		return s
+	}
+	var squares float64
+	for _, v := range samples {
+		squares += (v - s.Mean) * (v - s.Mean)
+	}
+	s.StdDev = math.Sqrt(squares / float64(s.N-1))
+	if s.Mean != 0 {
+		s.CV = s.StdDev / math.Abs(s.Mean)
+	}
+	half := tCritical95(s.N-1) * s.StdDev / math.Sqrt(float64(s.N))
+	s.CILow, s.CIHigh = s.Mean-half, s.Mean+half
+	return s
+}
+
+// tTable holds the two-sided 95% critical values of Student's t
+// distribution for 1 to 30 degrees of freedom.
+var tTable = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// tCritical95 returns the two-sided 95% critical value for df degrees of
+// freedom, using the normal distribution beyond the table.
+func tCritical95(df int) float64 {
+	if df >= 1 && df <= len(tTable) {
+		return tTable[df-1]
+	}
+	return 1.960
+}
+
+// MannWhitney runs the two-sided Mann-Whitney U test of whether a and b come
+// from the same distribution and returns U and the p-value. It uses the
+// normal approximation with a tie correction, which needs about eight or more
+// samples on each side to be accurate.
+func MannWhitney(a, b []float64) (u, p float64) {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+
+	type sample struct {
+		value float64
+		fromA bool
+	}
+	samples := make([]sample, 0, len(a)+len(b))
+	for _, v := range a {
+		samples = append(samples, sample{v, true})
+	}
+	for _, v := range b {
+		samples = append(samples, sample{v, false})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].value < samples[j].value })
+
+	// Tied values share the average of their ranks.
+	var rankSumA, ties float64
+	for i := 0; i < len(samples); {
+		j := i
+		for j < len(samples) && samples[j].value == samples[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if samples[k].fromA {
+				rankSumA += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	u1 := rankSumA - n1*(n1+1)/2
+	u = math.Min(u1, n1*n2-u1)
+
+	n := n1 + n2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return u, 1
+	}
+	// U is the smaller of the two statistics, so z is not positive; the
+	// continuity correction moves it towards zero.
+	z := math.Min(0, (u-n1*n2/2+0.5)/sigma)
+	return u, math.Min(1, math.Erfc(-z/math.Sqrt2))
+}
@@ -0,0 +1,104 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	alternating := make([]float64, 32)
+	for i := range alternating {
+		alternating[i] = float64(i%2) * 2
+	}
+	tests := []struct {
+		name    string
+		samples []float64
+		want    Summary
+	}{
+		{name: "no samples", samples: nil, want: Summary{}},
+		{name: "one sample", samples: []float64{5}, want: Summary{N: 1, Mean: 5, CILow: 5, CIHigh: 5}},
+		{
+			name:    "three samples",
+			samples: []float64{1, 2, 3},
+			want:    Summary{N: 3, Mean: 2, StdDev: 1, CV: 0.5, CILow: 2 - 4.303/math.Sqrt(3), CIHigh: 2 + 4.303/math.Sqrt(3)},
+		},
+		{
+			name:    "eight samples",
+			samples: []float64{2, 4, 4, 4, 5, 5, 7, 9},
+			want: Summary{
+				N: 8, Mean: 5, StdDev: math.Sqrt(32.0 / 7), CV: math.Sqrt(32.0/7) / 5,
+				CILow: 5 - 2.365*math.Sqrt(32.0/7)/math.Sqrt(8), CIHigh: 5 + 2.365*math.Sqrt(32.0/7)/math.Sqrt(8),
+			},
+		},
+		{
+			name:    "zero mean has no CV",
+			samples: []float64{-1, 1},
+			want:    Summary{N: 2, Mean: 0, StdDev: math.Sqrt2, CV: 0, CILow: -12.706, CIHigh: 12.706},
+		},
+		{
+			name:    "beyond the t table",
+			samples: alternating,
+			want: Summary{
+				N: 32, Mean: 1, StdDev: math.Sqrt(32.0 / 31), CV: math.Sqrt(32.0 / 31),
+				CILow: 1 - 1.960*math.Sqrt(32.0/31)/math.Sqrt(32), CIHigh: 1 + 1.960*math.Sqrt(32.0/31)/math.Sqrt(32),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Summarize(tt.samples)
+			if got.N != tt.want.N {
+				t.Errorf("N = %d, want %d", got.N, tt.want.N)
+			}
+			fields := []struct {
+				name      string
+				got, want float64
+			}{
+				{"Mean", got.Mean, tt.want.Mean},
+				{"StdDev", got.StdDev, tt.want.StdDev},
+				{"CV", got.CV, tt.want.CV},
+				{"CILow", got.CILow, tt.want.CILow},
+				{"CIHigh", got.CIHigh, tt.want.CIHigh},
+			}
+			for _, f := range fields {
+				if math.Abs(f.got-f.want) > 1e-9 {
+					t.Errorf("%s = %v, want %v", f.name, f.got, f.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMannWhitney(t *testing.T) {
+	seq := func(from, to, step int) []float64 {
+		var s []float64
+		for v := from; v <= to; v += step {
+			s = append(s, float64(v))
+		}
+		return s
+	}
+	tests := []struct {
+		name       string
+		a, b       []float64
+		wantU      float64
+		pMin, pMax float64
+	}{
+		{name: "empty side", a: nil, b: []float64{1, 2}, wantU: 0, pMin: 1, pMax: 1},
+		{name: "all tied", a: []float64{1, 1, 1}, b: []float64{1, 1, 1}, wantU: 4.5, pMin: 1, pMax: 1},
+		{name: "separated", a: seq(1, 8, 1), b: seq(9, 16, 1), wantU: 0, pMin: 0, pMax: 0.001},
+		{name: "separated the other way", a: seq(9, 16, 1), b: seq(1, 8, 1), wantU: 0, pMin: 0, pMax: 0.001},
+		{name: "interleaved", a: seq(1, 15, 2), b: seq(2, 16, 2), wantU: 28, pMin: 0.7, pMax: 0.75},
+		{name: "identical", a: seq(1, 10, 1), b: seq(1, 10, 1), wantU: 50, pMin: 1, pMax: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, p := MannWhitney(tt.a, tt.b)
+			if u != tt.wantU {
+				t.Errorf("U = %v, want %v", u, tt.wantU)
+			}
+			if p < tt.pMin || p > tt.pMax {
+				t.Errorf("p = %v, want between %v and %v", p, tt.pMin, tt.pMax)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"database-benchmark/internal/database"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// The histogram logs are written in the HdrHistogram interval log format, so
+// they can be read back with ReadHistogramLog or plotted with the usual
+// HdrHistogram tools. Timestamps are seconds relative to the start of the run
+// and, since the runner records microseconds, the interval max is reported in
+// milliseconds.
+
+// writeHistogramLog writes hists, one line per histogram tagged with its tag.
+func writeHistogramLog(w io.Writer, base time.Time, hists []*hdrhistogram.Histogram) error {
+	base = writeLogHeader(w, base)
+	for _, h := range hists {
+		payload, err := h.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+		if err != nil {
+			return err
+		}
+		start := time.UnixMilli(h.StartTimeMs()).Sub(base)
+		length := time.Duration(h.EndTimeMs()-h.StartTimeMs()) * time.Millisecond
+		if err := writeLogLine(w, h.Tag(), start, length, h.Max(), payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeIntervalLog writes the histogram of every interval window, untagged,
+// for a measured phase that started at measureStart.
+func writeIntervalLog(w io.Writer, base, measureStart time.Time, intervals []database.IntervalResult) error {
+	base = writeLogHeader(w, base)
+	for _, interval := range intervals {
+		if interval.Histogram == nil {
+			continue
+		}
+		start := measureStart.Add(interval.Start).Sub(base)
+		max := interval.MaxLatency.Microseconds()
+		if err := writeLogLine(w, "", start, interval.Duration, max, interval.Histogram); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLogHeader writes the log header and returns base truncated to the
+// millisecond precision of the log.
+func writeLogHeader(w io.Writer, base time.Time) time.Time {
+	base = base.Truncate(time.Millisecond)
+	baseSec := float64(base.UnixMilli()) / 1000
+	fmt.Fprintln(w, "#[Histogram log format version 1.3]")
+	fmt.Fprintf(w, "#[StartTime: %.3f (seconds since epoch), %s]\n", baseSec, base.Format(time.RFC3339))
+	fmt.Fprintf(w, "#[BaseTime: %.3f (seconds since epoch)]\n", baseSec)
+	fmt.Fprintln(w, `"StartTimestamp","Interval_Length","Interval_Max","Interval_Compressed_Histogram"`)
+	return base
+}
+
+func writeLogLine(w io.Writer, tag string, start, length time.Duration, max int64, payload []byte) error {
+	if tag != "" {
+		tag = "Tag=" + tag + ","
+	}
+	_, err := fmt.Fprintf(w, "%s%.3f,%.3f,%.3f,%s\n", tag, start.Seconds(), length.Seconds(), float64(max)/1000, payload)
+	return err
+}
+
+// ReadHistogramLog reads every histogram in the log at path, such as the
+// HistogramFile or IntervalFile of a run. Each histogram keeps its tag and
+// the period it covers.
+func ReadHistogramLog(path string) ([]*hdrhistogram.Histogram, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var hists []*hdrhistogram.Histogram
+	reader := hdrhistogram.NewHistogramLogReader(file)
+	for {
+		h, err := reader.NextIntervalHistogram()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if h == nil {
+			return hists, nil
+		}
+		hists = append(hists, h)
+	}
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"database-benchmark/internal/database"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// histogram returns a histogram tagged tag over [start, end) holding values,
+// in microseconds.
+func histogram(tag string, start, end time.Time, values ...int64) *hdrhistogram.Histogram {
+	h := hdrhistogram.New(1, int64(time.Hour/time.Microsecond), 3)
+	for _, v := range values {
+		h.RecordValue(v)
+	}
+	h.SetTag(tag)
+	h.SetStartTimeMs(start.UnixMilli())
+	h.SetEndTimeMs(end.UnixMilli())
+	return h
+}
+
+func TestHistogramLogRoundTrip(t *testing.T) {
+	base := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	measureStart := base.Add(2 * time.Second)
+	measureEnd := measureStart.Add(10 * time.Second)
+	tests := []struct {
+		name  string
+		hists []*hdrhistogram.Histogram
+	}{
+		{
+			name:  "total only",
+			hists: []*hdrhistogram.Histogram{histogram("total", measureStart, measureEnd, 100, 200, 300, 5000)},
+		},
+		{
+			name: "stages and operations",
+			hists: []*hdrhistogram.Histogram{
+				histogram("total", measureStart, measureEnd, 100, 200, 300),
+				histogram("stage-0", measureStart, measureStart.Add(5*time.Second), 100),
+				histogram("stage-1", measureStart.Add(5*time.Second), measureEnd, 200, 300),
+				histogram("op-insert_order", measureStart, measureEnd, 1500, 2500),
+			},
+		},
+		{
+			name:  "empty histogram",
+			hists: []*hdrhistogram.Histogram{histogram("total", measureStart, measureEnd)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), HistogramFile)
+			err := writeFile(path, func(file *os.File) error {
+				return writeHistogramLog(file, base, tt.hists)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadHistogramLog(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.hists) {
+				t.Fatalf("read %d histograms, want %d", len(got), len(tt.hists))
+			}
+			for i, want := range tt.hists {
+				h := got[i]
+				if h.Tag() != want.Tag() {
+					t.Errorf("histogram %d tag = %q, want %q", i, h.Tag(), want.Tag())
+				}
+				if h.StartTimeMs() != want.StartTimeMs() || h.EndTimeMs() != want.EndTimeMs() {
+					t.Errorf("%s covers %d-%d, want %d-%d", want.Tag(), h.StartTimeMs(), h.EndTimeMs(), want.StartTimeMs(), want.EndTimeMs())
+				}
+				if !h.Equals(want) {
+					t.Errorf("%s values differ after the round trip", want.Tag())
+				}
+			}
+		})
+	}
+}
+
+func TestIntervalLogRoundTrip(t *testing.T) {
+	base := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	measureStart := base.Add(2 * time.Second)
+	window := func(i int, values ...int64) (database.IntervalResult, *hdrhistogram.Histogram) {
+		start := measureStart.Add(time.Duration(i) * time.Second)
+		h := histogram("", start, start.Add(time.Second), values...)
+		encoded, err := h.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		interval := database.IntervalResult{Start: time.Duration(i) * time.Second, Duration: time.Second, Histogram: encoded}
+		interval.MaxLatency = time.Duration(h.Max()) * time.Microsecond
+		return interval, h
+	}
+	first, firstHist := window(0, 100, 200)
+	second, secondHist := window(1, 300)
+	third, thirdHist := window(2)
+
+	tests := []struct {
+		name      string
+		intervals []database.IntervalResult
+		want      []*hdrhistogram.Histogram
+	}{
+		{name: "one window", intervals: []database.IntervalResult{first}, want: []*hdrhistogram.Histogram{firstHist}},
+		{
+			name:      "windows in order",
+			intervals: []database.IntervalResult{first, second, third},
+			want:      []*hdrhistogram.Histogram{firstHist, secondHist, thirdHist},
+		},
+		{
+			name:      "windows without a histogram are skipped",
+			intervals: []database.IntervalResult{first, {Start: time.Second, Duration: time.Second}, third},
+			want:      []*hdrhistogram.Histogram{firstHist, thirdHist},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), IntervalFile)
+			err := writeFile(path, func(file *os.File) error {
+				return writeIntervalLog(file, base, measureStart, tt.intervals)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadHistogramLog(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("read %d windows, want %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got[i].StartTimeMs() != want.StartTimeMs() {
+					t.Errorf("window %d starts at %d, want %d", i, got[i].StartTimeMs(), want.StartTimeMs())
+				}
+				if got[i].TotalCount() != want.TotalCount() || got[i].Max() != want.Max() {
+					t.Errorf("window %d has %d values up to %d, want %d up to %d",
+						i, got[i].TotalCount(), got[i].Max(), want.TotalCount(), want.Max())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Host describes the machine the benchmark ran on.
+type Host struct {
+	Hostname    string
+	OS          string
+	Arch        string
+	CPUs        int
+	CPUModel    string `json:",omitempty"`
+	MemoryBytes uint64 `json:",omitempty"`
+}
+
+// CurrentHost describes this machine. The CPU model and memory size are only
+// known on Linux.
+func CurrentHost() Host {
+	host := Host{
+		OS:   runtime.GOOS,
+		Arch: runtime.GOARCH,
+		CPUs: runtime.NumCPU(),
+	}
+	host.Hostname, _ = os.Hostname()
+	host.CPUModel = procField("/proc/cpuinfo", "model name")
+	if mem := procField("/proc/meminfo", "MemTotal"); mem != "" {
+		// MemTotal is reported in kB
+		if kb, err := strconv.ParseUint(strings.TrimSuffix(mem, " kB"), 10, 64); err == nil {
+			host.MemoryBytes = kb * 1024
+		}
+	}
+	return host
+}
+
+// procField returns the value of the first "key: value" line for key in a
+// /proc file, or an empty string.
+func procField(path, key string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.TrimSpace(name) == key {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// ToolCommit returns the git commit the benchmark tool was built from, with a
+// "-dirty" suffix for uncommitted changes. It is empty for binaries built
+// without version control information, such as with go run.
+func ToolCommit() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return revision
+}
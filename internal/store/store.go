@@ -0,0 +1,247 @@
+// Package store keeps every benchmark run on disk together with everything
+// needed to tell later how its numbers were produced.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"database-benchmark/internal/config"
+	"database-benchmark/internal/output"
+	"database-benchmark/internal/runner"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultRoot is the directory that holds one subdirectory per run.
+const DefaultRoot = "results"
+
+// Files in a run directory.
+const (
+	ResultFile    = "result.json"
+	MetadataFile  = "metadata.json"
+	ConfigFile    = "config.yaml"
+	HistogramFile = "histograms.hlog"
+	IntervalFile  = "intervals.hlog"
+)
+
+// Metadata records how a run was produced.
+type Metadata struct {
+	StartedAt time.Time
+	DB        string
+	Workload  string
+	Test      string
+	// Args is the command line and Flags the effective value of every
+	// flag, including defaults.
+	Args  []string
+	Flags map[string]string
+	// Options are the runner options the flags resolved to.
+	Options runner.Options
+	Seed    int64
+	// ToolCommit is the git commit of the benchmark tool, if known.
+	ToolCommit    string
+	GoVersion     string
+	Host          Host
+	ServerVersion string
+}
+
+// Save writes run to a new directory below root named after its start time,
+// test and concurrency, and returns the directory. The config is stored with
+// passwords redacted.
+func Save(root string, run output.Run, meta Metadata, cfg *config.Config) (string, error) {
+	name := fmt.Sprintf("%s-%s-%s-%s-c%d", meta.StartedAt.Format("20060102T150405.000"), run.DB, run.Workload, run.Test, meta.Options.Concurrency)
+	dir, err := makeRunDir(root, name)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeFile(filepath.Join(dir, ResultFile), func(file *os.File) error {
+		return output.Write(file, output.JSON, run)
+	})
+	if err != nil {
+		return dir, err
+	}
+	err = writeFile(filepath.Join(dir, MetadataFile), func(file *os.File) error {
+		enc := json.NewEncoder(file)
+		enc.SetIndent("", "  ")
+		return enc.Encode(meta)
+	})
+	if err != nil {
+		return dir, err
+	}
+	err = writeFile(filepath.Join(dir, ConfigFile), func(file *os.File) error {
+		return yaml.NewEncoder(file).Encode(cfg.Redacted())
+	})
+	if err != nil {
+		return dir, err
+	}
+	if len(run.Histograms) == 0 {
+		return dir, nil
+	}
+	err = writeFile(filepath.Join(dir, HistogramFile), func(file *os.File) error {
+		return writeHistogramLog(file, meta.StartedAt, run.Histograms)
+	})
+	if err != nil || len(run.Intervals) == 0 {
+		return dir, err
+	}
+	// The overall histogram is stamped with the measured phase the interval
+	// offsets are relative to.
+	measureStart := time.UnixMilli(run.Histograms[0].StartTimeMs())
+	err = writeFile(filepath.Join(dir, IntervalFile), func(file *os.File) error {
+		return writeIntervalLog(file, meta.StartedAt, measureStart, run.Intervals)
+	})
+	return dir, err
+}
+
+// Load reads the runs in path, which is either a run directory or a file of
+// results written with --output=json.
+func Load(path string) ([]output.Run, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		path = filepath.Join(path, ResultFile)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var runs []output.Run
+	dec := json.NewDecoder(file)
+	for dec.More() {
+		var run output.Run
+		if err := dec.Decode(&run); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		runs = append(runs, run)
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("no results in %s", path)
+	}
+	return runs, nil
+}
+
+// Entry is a run read back from the store. Dir and Metadata are only set for
+// runs read from a run directory.
+type Entry struct {
+	Dir      string
+	Run      output.Run
+	Metadata *Metadata
+}
+
+// List reads the runs in path, which is a run directory, a directory of run
+// directories such as the store root, or a file of results written with
+// --output=json. Runs in a directory of runs are listed oldest first;
+// subdirectories that hold no run are skipped.
+func List(path string) ([]Entry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		runs, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]Entry, len(runs))
+		for i, run := range runs {
+			entries[i] = Entry{Run: run}
+		}
+		return entries, nil
+	}
+	if _, err := os.Stat(filepath.Join(path, ResultFile)); err == nil {
+		entry, err := loadEntry(path)
+		if err != nil {
+			return nil, err
+		}
+		return []Entry{entry}, nil
+	}
+
+	// Run directory names start with the start time, so reading them in
+	// name order lists the runs oldest first.
+	dirs, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		runDir := filepath.Join(path, dir.Name())
+		if _, err := os.Stat(filepath.Join(runDir, ResultFile)); err != nil {
+			continue
+		}
+		entry, err := loadEntry(runDir)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// loadEntry reads the run in dir, which holds a single result, and its
+// metadata if there is any.
+func loadEntry(dir string) (Entry, error) {
+	runs, err := Load(dir)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{Dir: dir, Run: runs[0]}
+	data, err := os.ReadFile(filepath.Join(dir, MetadataFile))
+	if os.IsNotExist(err) {
+		return entry, nil
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	entry.Metadata = &Metadata{}
+	if err := json.Unmarshal(data, entry.Metadata); err != nil {
+		return Entry{}, fmt.Errorf("failed to read %s: %w", filepath.Join(dir, MetadataFile), err)
+	}
+	return entry, nil
+}
+
+// makeRunDir creates a new directory below root called name, or name with a
+// numeric suffix if another run already took it, so that no run is ever
+// written over another.
+func makeRunDir(root, name string) (string, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", err
+	}
+	for i := 1; ; i++ {
+		dir := filepath.Join(root, name)
+		if i > 1 {
+			dir = fmt.Sprintf("%s-%d", dir, i)
+		}
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			return dir, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+	}
+}
+
+// writeFile creates path and fills it with write.
+func writeFile(path string, write func(file *os.File) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -5,17 +5,31 @@ import (
 	"database-benchmark/internal/database"
 	"fmt"
 	"log"
-	"sync"
 	"time"
 
-	"github.com/HdrHistogram/hdrhistogram-go"
-	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
-type DashboardQueryTest struct{}
+// numSeededEvents is the number of events Setup inserts.
+const numSeededEvents = 10000
+
+type DashboardQueryTest struct {
+	seed     int64
+	progress database.SeedProgress
+}
+
+// Seed sets the seed of the data generated in Setup.
+func (t *DashboardQueryTest) Seed(seed int64) {
+	t.seed = seed
+}
+
+// SetSeedProgress sets the function Setup reports the rows it inserted to.
+func (t *DashboardQueryTest) SetSeedProgress(progress database.SeedProgress) {
+	t.progress = progress
+}
 
 func (t *DashboardQueryTest) Setup(ctx context.Context, db database.DatabaseDriver, logger *log.Logger) error {
+	rng := database.NewRand(t.seed, database.SetupStream)
 	return db.ExecuteTx(ctx, func(tx interface{}) error {
 		ctx = context.WithValue(ctx, "tx", tx)
 
@@ -32,8 +46,8 @@ func (t *DashboardQueryTest) Setup(ctx context.Context, db database.DatabaseDriv
 			}
 		}
 
-		for i := 0; i < 10000; i++ {
-			eventID := uuid.New().String()
+		for i := 0; i < numSeededEvents; i++ {
+			eventID := database.NewUUID(rng)
 			userID := fmt.Sprintf("user%d", i%1000)
 			productID := fmt.Sprintf("product%d", i%100)
 			region := fmt.Sprintf("region%d", i%10)
@@ -63,70 +77,35 @@ func (t *DashboardQueryTest) Setup(ctx context.Context, db database.DatabaseDriv
 					return err
 				}
 			}
+			t.progress.Report("analytics_events", i+1, numSeededEvents)
 		}
 
 		return nil
 	})
 }
 
-func (t *DashboardQueryTest) Run(ctx context.Context, db database.DatabaseDriver, concurrency int, duration time.Duration, logger *log.Logger) (*database.Result, error) {
-	var wg sync.WaitGroup
-	histogram := hdrhistogram.New(1, 10000, 3)
-	deadline := time.Now().Add(duration)
-	result := &database.Result{}
-	var mu sync.Mutex
-
-	for i := 0; i < concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for time.Now().Before(deadline) {
-				startTime := time.Now()
-				var err error
-				if _, ok := db.(*database.MongoDriver); ok {
-					// For MongoDB, use aggregation pipeline
-					pipeline := []bson.M{
-						{"$match": bson.M{"event_timestamp": bson.M{"$gt": time.Now().Add(-1 * time.Hour)}}},
-						{"$group": bson.M{"_id": "$region", "total_metric": bson.M{"$sum": "$metric_value"}}},
-					}
-					rows, queryErr := db.QueryContext(ctx, "analytics_events", pipeline)
-					err = queryErr
-					if err == nil {
-						rows.Close()
-					}
-				} else {
-					query := "SELECT region, SUM(metric_value) FROM analytics_events WHERE event_timestamp > $1 GROUP BY region"
-					if _, ok := db.(*database.MySQLDriver); ok {
-						query = "SELECT region, SUM(metric_value) FROM analytics_events WHERE event_timestamp > ? GROUP BY region"
-					}
-					rows, queryErr := db.QueryContext(ctx, query, time.Now().Add(-1*time.Hour))
-					err = queryErr
-					if err == nil {
-						rows.Close()
-					}
-				}
-
-				mu.Lock()
-				if err != nil {
-					result.Errors++
-				} else {
-					result.Operations++
-					histogram.RecordValue(time.Since(startTime).Milliseconds())
-				}
-				mu.Unlock()
-			}
-		}()
+func (t *DashboardQueryTest) Operation(ctx context.Context, db database.DatabaseDriver, worker *database.Worker) error {
+	var rows database.Rows
+	var err error
+	if _, ok := db.(*database.MongoDriver); ok {
+		// For MongoDB, use aggregation pipeline
+		pipeline := []bson.M{
+			{"$match": bson.M{"event_timestamp": bson.M{"$gt": time.Now().Add(-1 * time.Hour)}}},
+			{"$group": bson.M{"_id": "$region", "total_metric": bson.M{"$sum": "$metric_value"}}},
+		}
+		rows, err = db.QueryContext(ctx, "analytics_events", pipeline)
+	} else {
+		query := "SELECT region, SUM(metric_value) FROM analytics_events WHERE event_timestamp > $1 GROUP BY region"
+		if _, ok := db.(*database.MySQLDriver); ok {
+			query = "SELECT region, SUM(metric_value) FROM analytics_events WHERE event_timestamp > ? GROUP BY region"
+		}
+		rows, err = db.QueryContext(ctx, query, time.Now().Add(-1*time.Hour))
 	}
-
-	wg.Wait()
-
-	result.TotalTime = duration
-	result.Throughput = float64(result.Operations) / duration.Seconds()
-	result.P95Latency = time.Duration(histogram.ValueAtQuantile(95)) * time.Millisecond
-	result.P99Latency = time.Duration(histogram.ValueAtQuantile(99)) * time.Millisecond
-	result.AverageLatency = time.Duration(histogram.Mean()) * time.Millisecond
-
-	return result, nil
+	if err != nil {
+		return err
+	}
+	rows.Close()
+	return rows.Err()
 }
 
 func (t *DashboardQueryTest) Teardown(ctx context.Context, db database.DatabaseDriver, logger *log.Logger) error {
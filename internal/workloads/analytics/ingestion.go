@@ -5,19 +5,22 @@ import (
 	"database-benchmark/internal/database"
 	"fmt"
 	"log"
-	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 const (
 	NumEvents = 100000
 )
 
-type IngestionTest struct{}
+type IngestionTest struct {
+	ingested int64
+}
 
 func (t *IngestionTest) Setup(ctx context.Context, db database.DatabaseDriver, logger *log.Logger) error {
+	atomic.StoreInt64(&t.ingested, 0)
 	return db.ExecuteTx(ctx, func(tx interface{}) error {
 		ctx = context.WithValue(ctx, "tx", tx)
 
@@ -32,45 +35,38 @@ func (t *IngestionTest) Setup(ctx context.Context, db database.DatabaseDriver, l
 	})
 }
 
-func (t *IngestionTest) Run(ctx context.Context, db database.DatabaseDriver, concurrency int, duration time.Duration, logger *log.Logger) (*database.Result, error) {
-	var wg sync.WaitGroup
-	startTime := time.Now()
-
-	for i := 0; i < concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			db.ExecuteTx(context.Background(), func(tx interface{}) error {
-				for i := 0; i < NumEvents/concurrency; i++ {
-					eventID := uuid.New().String()
-					userID := fmt.Sprintf("user%d", i%1000)
-					productID := fmt.Sprintf("product%d", i%100)
-					region := fmt.Sprintf("region%d", i%10)
-					metricValue := float64(i)
-											query := "INSERT INTO analytics_events (event_id, event_timestamp, user_id, product_id, region, metric_value) VALUES ($1, $2, $3, $4, $5, $6)"
-											if _, ok := db.(*database.MySQLDriver); ok {
-												query = "INSERT INTO analytics_events (event_id, event_timestamp, user_id, product_id, region, metric_value) VALUES (?, ?, ?, ?, ?, ?)"
-											}
-											_, err := db.ExecContext(context.WithValue(context.Background(), "tx", tx), query, eventID, time.Now(), userID, productID, region, metricValue)
-											if err != nil {						return err
-					}
-				}
-				return nil
-			})
-		}()
+// Operation inserts one event. The run ends once NumEvents events have been
+// claimed across all workers.
+func (t *IngestionTest) Operation(ctx context.Context, db database.DatabaseDriver, worker *database.Worker) error {
+	i := atomic.AddInt64(&t.ingested, 1) - 1
+	if i >= NumEvents {
+		return database.ErrWorkloadDone
 	}
 
-	wg.Wait()
+	eventID := database.NewUUID(worker.Rand)
+	userID := fmt.Sprintf("user%d", i%1000)
+	productID := fmt.Sprintf("product%d", i%100)
+	region := fmt.Sprintf("region%d", i%10)
+	metricValue := float64(i)
 
-	totalTime := time.Since(startTime)
-	ingestionRate := float64(NumEvents) / totalTime.Seconds()
-
-	result := &database.Result{
-		TotalTime:  totalTime,
-		Throughput: ingestionRate,
+	if _, ok := db.(*database.MongoDriver); ok {
+		_, err := db.ExecContext(ctx, "analytics_events", bson.M{
+			"_id":             eventID,
+			"event_timestamp": time.Now(),
+			"user_id":         userID,
+			"product_id":      productID,
+			"region":          region,
+			"metric_value":    metricValue,
+		})
+		return err
 	}
 
-	return result, nil
+	query := "INSERT INTO analytics_events (event_id, event_timestamp, user_id, product_id, region, metric_value) VALUES ($1, $2, $3, $4, $5, $6)"
+	if _, ok := db.(*database.MySQLDriver); ok {
+		query = "INSERT INTO analytics_events (event_id, event_timestamp, user_id, product_id, region, metric_value) VALUES (?, ?, ?, ?, ?, ?)"
+	}
+	_, err := db.ExecContext(ctx, query, eventID, time.Now(), userID, productID, region, metricValue)
+	return err
 }
 
 func (t *IngestionTest) Teardown(ctx context.Context, db database.DatabaseDriver, logger *log.Logger) error {
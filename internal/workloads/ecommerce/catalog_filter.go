@@ -5,36 +5,40 @@ import (
 	"database-benchmark/internal/database"
 	"fmt"
 	"log"
-	"math/rand"
-	"sync"
 	"time"
 
-	"github.com/HdrHistogram/hdrhistogram-go"
-	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
-type CatalogFilterTest struct{}
+type CatalogFilterTest struct {
+	seed int64
+}
+
+// Seed sets the seed of the data generated in Setup.
+func (t *CatalogFilterTest) Seed(seed int64) {
+	t.seed = seed
+}
 
 func (t *CatalogFilterTest) Setup(ctx context.Context, db database.DatabaseDriver, logger *log.Logger) error {
+	rng := database.NewRand(t.seed, database.SetupStream)
 	if _, ok := db.(*database.MongoDriver); ok {
 		// MongoDB does not use SQL schemas, collections are created implicitly
 		return db.ExecuteTx(ctx, func(tx interface{}) error {
 			ctx = context.WithValue(ctx, "tx", tx)
 			for i := 0; i < 100; i++ {
-				productID := uuid.New().String()
+				productID := database.NewUUID(rng)
 				_, err := db.ExecContext(ctx, "products", bson.M{"_id": productID, "name": fmt.Sprintf("product-%d", i), "inventory": 100})
 				if err != nil {
 					return err
 				}
-				for j := 0; j < rand.Intn(10); j++ {
-					orderID := uuid.New().String()
-					userID := uuid.New().String()
+				for j := 0; j < rng.Intn(10); j++ {
+					orderID := database.NewUUID(rng)
+					userID := database.NewUUID(rng)
 					_, err = db.ExecContext(ctx, "orders", bson.M{"_id": orderID, "user_id": userID, "created_at": time.Now()})
 					if err != nil {
 						return err
 					}
-					orderItemID := uuid.New().String()
+					orderItemID := database.NewUUID(rng)
 					_, err = db.ExecContext(ctx, "order_items", bson.M{"_id": orderItemID, "order_id": orderID, "product_id": productID, "quantity": 1})
 					if err != nil {
 						return err
@@ -61,7 +65,7 @@ func (t *CatalogFilterTest) Setup(ctx context.Context, db database.DatabaseDrive
 		}
 
 		for i := 0; i < 100; i++ {
-			productID := uuid.New().String()
+			productID := database.NewUUID(rng)
 			query := "INSERT INTO products (id, name, inventory) VALUES ($1, $2, $3)"
 			if _, ok := db.(*database.MySQLDriver); ok {
 				query = "INSERT INTO products (id, name, inventory) VALUES (?, ?, ?)"
@@ -70,9 +74,9 @@ func (t *CatalogFilterTest) Setup(ctx context.Context, db database.DatabaseDrive
 			if err != nil {
 				return err
 			}
-			for j := 0; j < rand.Intn(10); j++ {
-				orderID := uuid.New().String()
-				userID := uuid.New().String()
+			for j := 0; j < rng.Intn(10); j++ {
+				orderID := database.NewUUID(rng)
+				userID := database.NewUUID(rng)
 				query := "INSERT INTO orders (id, user_id, created_at) VALUES ($1, $2, $3)"
 				if _, ok := db.(*database.MySQLDriver); ok {
 					query = "INSERT INTO orders (id, user_id, created_at) VALUES (?, ?, ?)"
@@ -81,7 +85,7 @@ func (t *CatalogFilterTest) Setup(ctx context.Context, db database.DatabaseDrive
 				if err != nil {
 					return err
 				}
-				orderItemID := uuid.New().String()
+				orderItemID := database.NewUUID(rng)
 				query = "INSERT INTO order_items (id, order_id, product_id, quantity) VALUES ($1, $2, $3, $4)"
 				if _, ok := db.(*database.MySQLDriver); ok {
 					query = "INSERT INTO order_items (id, order_id, product_id, quantity) VALUES (?, ?, ?, ?)"
@@ -96,55 +100,19 @@ func (t *CatalogFilterTest) Setup(ctx context.Context, db database.DatabaseDrive
 	})
 }
 
-func (t *CatalogFilterTest) Run(ctx context.Context, db database.DatabaseDriver, concurrency int, duration time.Duration, logger *log.Logger) (*database.Result, error) {
-	var wg sync.WaitGroup
-	histogram := hdrhistogram.New(1, 10000, 3)
-	deadline := time.Now().Add(duration)
-	result := &database.Result{}
-	var mu sync.Mutex
-
-	for i := 0; i < concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for time.Now().Before(deadline) {
-				startTime := time.Now()
-				var err error
-				if _, ok := db.(*database.MongoDriver); ok {
-					rows, queryErr := db.QueryContext(ctx, "products", bson.M{"order_items": bson.M{"$size": bson.M{"$gt": 5}}})
-					err = queryErr
-					if err == nil {
-						rows.Close()
-					}
-				} else {
-					rows, queryErr := db.QueryContext(ctx, "SELECT p.id FROM products p JOIN order_items oi ON p.id = oi.product_id GROUP BY p.id HAVING COUNT(oi.id) > 5")
-					err = queryErr
-					if err == nil {
-						rows.Close()
-					}
-				}
-
-				mu.Lock()
-				if err != nil {
-					result.Errors++
-				} else {
-					result.Operations++
-					histogram.RecordValue(time.Since(startTime).Milliseconds())
-				}
-				mu.Unlock()
-			}
-		}()
+func (t *CatalogFilterTest) Operation(ctx context.Context, db database.DatabaseDriver, worker *database.Worker) error {
+	var rows database.Rows
+	var err error
+	if _, ok := db.(*database.MongoDriver); ok {
+		rows, err = db.QueryContext(ctx, "products", bson.M{"order_items": bson.M{"$size": bson.M{"$gt": 5}}})
+	} else {
+		rows, err = db.QueryContext(ctx, "SELECT p.id FROM products p JOIN order_items oi ON p.id = oi.product_id GROUP BY p.id HAVING COUNT(oi.id) > 5")
 	}
-
-	wg.Wait()
-
-	result.TotalTime = duration
-	result.Throughput = float64(result.Operations) / duration.Seconds()
-	result.P95Latency = time.Duration(histogram.ValueAtQuantile(95)) * time.Millisecond
-	result.P99Latency = time.Duration(histogram.ValueAtQuantile(99)) * time.Millisecond
-	result.AverageLatency = time.Duration(histogram.Mean()) * time.Millisecond
-
-	return result, nil
+	if err != nil {
+		return err
+	}
+	rows.Close()
+	return rows.Err()
 }
 
 func (t *CatalogFilterTest) Teardown(ctx context.Context, db database.DatabaseDriver, logger *log.Logger) error {
@@ -179,4 +147,4 @@ func (t *CatalogFilterTest) Teardown(ctx context.Context, db database.DatabaseDr
 		}
 		return nil
 	})
-}
\ No newline at end of file
+}
@@ -7,8 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"sync"
-	"time"
+	"sync/atomic"
 
 	"github.com/jackc/pgx/v5/pgconn"
 	"go.mongodb.org/mongo-driver/bson"
@@ -17,7 +16,15 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
-type InventoryUpdateTest struct{}
+// InitialInventory is the stock of the single product that all workers
+// decrement concurrently.
+const InitialInventory = 10000
+
+var errInventoryDepleted = errors.New("inventory depleted")
+
+type InventoryUpdateTest struct {
+	committed int64
+}
 
 type Tx interface {
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
@@ -25,12 +32,13 @@ type Tx interface {
 
 func (t *InventoryUpdateTest) Setup(ctx context.Context, db database.DatabaseDriver, logger *log.Logger) error {
 	logger.Println("Starting setup")
+	atomic.StoreInt64(&t.committed, 0)
 	if mongoDriver, ok := db.(*database.MongoDriver); ok {
 		return mongoDriver.ExecuteTx(ctx, func(tx interface{}) error {
 			ctx = context.WithValue(ctx, "tx", tx)
 			// Drop collection if it exists to ensure a clean state
 			mongoDriver.ExecContext(ctx, "products", bson.M{})
-			_, err := mongoDriver.ExecContext(ctx, "products", bson.M{"_id": "product1", "name": "test product", "inventory": 10000})
+			_, err := mongoDriver.ExecContext(ctx, "products", bson.M{"_id": "product1", "name": "test product", "inventory": InitialInventory})
 			return err
 		})
 	}
@@ -71,7 +79,7 @@ func (t *InventoryUpdateTest) Setup(ctx context.Context, db database.DatabaseDri
 		if _, ok := db.(*database.MySQLDriver); ok {
 			query = "INSERT INTO products (id, name, inventory) VALUES (?, ?, ?)"
 		}
-		_, err := sqlTx.ExecContext(ctx, query, "product1", "test product", 10000)
+		_, err := sqlTx.ExecContext(ctx, query, "product1", "test product", InitialInventory)
 		return err
 	})
 }
@@ -97,92 +105,59 @@ func (r pgxResult) RowsAffected() (int64, error) {
 	return r.CommandTag.RowsAffected(), nil
 }
 
-func (t *InventoryUpdateTest) Run(ctx context.Context, db database.DatabaseDriver, concurrency int, duration time.Duration, logger *log.Logger) (*database.Result, error) {
-	var wg sync.WaitGroup
-	startTime := time.Now()
-	result := &database.Result{}
-	var mu sync.Mutex
-
-	// We'll use a context that can be canceled once the inventory reaches zero.
-	runCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	for i := 0; i < concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for {
-				select {
-				case <-runCtx.Done():
-					return
-				default:
-					err := db.ExecuteTx(runCtx, func(tx interface{}) error {
-						if mongoDriver, ok := db.(*database.MongoDriver); ok {
-							txCtx := context.WithValue(runCtx, "tx", tx)
-							res, err := mongoDriver.ExecContext(txCtx, "products", bson.M{"_id": "product1", "inventory": bson.M{"$gt": 0}}, bson.M{"$inc": bson.M{"inventory": -1}})
-							if err != nil {
-								return err
-							}
-							if updateResult, ok := res.(*mongo.UpdateResult); ok && updateResult.ModifiedCount == 0 {
-								return errors.New("inventory depleted")
-							}
-							return nil
-						}
-
-						var sqlTx Tx
-						var ok bool
-						if sqlTx, ok = tx.(Tx); !ok {
-							if pgxTx, pgxOK := tx.(pgx.Tx); pgxOK {
-								sqlTx = &pgxTxAdapter{pgxTx}
-							} else {
-								return fmt.Errorf("unexpected transaction type: %T", tx)
-							}
-						}
-						query := "UPDATE products SET inventory = inventory - 1 WHERE id = $1 AND inventory > 0"
-						if _, ok := db.(*database.MySQLDriver); ok {
-							query = "UPDATE products SET inventory = inventory - 1 WHERE id = ? AND inventory > 0"
-						}
-						res, err := sqlTx.ExecContext(runCtx, query, "product1")
-						if err != nil {
-							return err
-						}
-						rowsAffected, err := res.RowsAffected()
-						if err != nil {
-							return err
-						}
-						if rowsAffected == 0 {
-							return errors.New("inventory depleted")
-						}
-						return nil
-					})
-
-					mu.Lock()
-					if err != nil {
-						if err.Error() == "inventory depleted" {
-							cancel()
-							mu.Unlock()
-							return
-						}
-						var pgErr *pgconn.PgError
-						if errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01") {
-							// Serialization error, just retry
-						} else {
-							result.Errors++
-						}
-					} else {
-						result.Operations++
-					}
-					mu.Unlock()
-				}
+func (t *InventoryUpdateTest) Operation(ctx context.Context, db database.DatabaseDriver, worker *database.Worker) error {
+	err := db.ExecuteTx(ctx, func(tx interface{}) error {
+		if mongoDriver, ok := db.(*database.MongoDriver); ok {
+			txCtx := context.WithValue(ctx, "tx", tx)
+			res, err := mongoDriver.ExecContext(txCtx, "products", bson.M{"_id": "product1", "inventory": bson.M{"$gt": 0}}, bson.M{"$inc": bson.M{"inventory": -1}})
+			if err != nil {
+				return err
 			}
-		}()
-	}
+			if updateResult, ok := res.(*mongo.UpdateResult); ok && updateResult.ModifiedCount == 0 {
+				return errInventoryDepleted
+			}
+			return nil
+		}
+
+		var sqlTx Tx
+		var ok bool
+		if sqlTx, ok = tx.(Tx); !ok {
+			if pgxTx, pgxOK := tx.(pgx.Tx); pgxOK {
+				sqlTx = &pgxTxAdapter{pgxTx}
+			} else {
+				return fmt.Errorf("unexpected transaction type: %T", tx)
+			}
+		}
+		query := "UPDATE products SET inventory = inventory - 1 WHERE id = $1 AND inventory > 0"
+		if _, ok := db.(*database.MySQLDriver); ok {
+			query = "UPDATE products SET inventory = inventory - 1 WHERE id = ? AND inventory > 0"
+		}
+		res, err := sqlTx.ExecContext(ctx, query, "product1")
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return errInventoryDepleted
+		}
+		return nil
+	})
 
-	wg.Wait()
-	result.TotalTime = time.Since(startTime)
-	result.Throughput = float64(result.Operations) / result.TotalTime.Seconds()
+	if errors.Is(err, errInventoryDepleted) {
+		return database.ErrWorkloadDone
+	}
+	if err == nil {
+		atomic.AddInt64(&t.committed, 1)
+	}
+	return err
+}
 
-	// Final integrity check
+// Verify checks that every committed decrement is reflected in the final
+// inventory, so that no update was lost or applied twice.
+func (t *InventoryUpdateTest) Verify(ctx context.Context, db database.DatabaseDriver, logger *log.Logger) (bool, error) {
 	var finalInventory int
 	if _, ok := db.(*database.MongoDriver); ok {
 		var product struct {
@@ -190,7 +165,7 @@ func (t *InventoryUpdateTest) Run(ctx context.Context, db database.DatabaseDrive
 		}
 		row := db.QueryRowContext(ctx, "products", bson.M{"_id": "product1"})
 		if err := row.Scan(&product); err != nil {
-			return nil, fmt.Errorf("failed to read final inventory: %w", err)
+			return false, fmt.Errorf("failed to read final inventory: %w", err)
 		}
 		finalInventory = product.Inventory
 	} else {
@@ -200,16 +175,16 @@ func (t *InventoryUpdateTest) Run(ctx context.Context, db database.DatabaseDrive
 		}
 		row := db.QueryRowContext(ctx, query, "product1")
 		if err := row.Scan(&finalInventory); err != nil {
-			return nil, fmt.Errorf("failed to read final inventory: %w", err)
+			return false, fmt.Errorf("failed to read final inventory: %w", err)
 		}
 	}
 
-	result.DataIntegrity = finalInventory == 0
-	if !result.DataIntegrity {
-		logger.Printf("Data integrity check failed: final inventory is %d, expected 0\n", finalInventory)
+	expected := InitialInventory - int(atomic.LoadInt64(&t.committed))
+	if finalInventory != expected {
+		logger.Printf("Data integrity check failed: final inventory is %d, expected %d\n", finalInventory, expected)
+		return false, nil
 	}
-
-	return result, nil
+	return true, nil
 }
 
 func (t *InventoryUpdateTest) Teardown(ctx context.Context, db database.DatabaseDriver, logger *log.Logger) error {
@@ -3,16 +3,11 @@ package socialmedia
 import (
 	"context"
 	"database-benchmark/internal/database"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
-	"strings"
-	"sync"
 	"time"
 
-	"github.com/HdrHistogram/hdrhistogram-go"
-	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -21,7 +16,14 @@ const (
 	NumFollows = 1000
 )
 
-type FanOutOnWriteTest struct{}
+type FanOutOnWriteTest struct {
+	seed int64
+}
+
+// Seed sets the seed of the post published in Setup.
+func (t *FanOutOnWriteTest) Seed(seed int64) {
+	t.seed = seed
+}
 
 func (t *FanOutOnWriteTest) Setup(ctx context.Context, db database.DatabaseDriver, logger *log.Logger) error {
 	var dbType string
@@ -102,196 +104,148 @@ func (t *FanOutOnWriteTest) Setup(ctx context.Context, db database.DatabaseDrive
 		}
 	}
 
-	return nil
+	// The fan-out write happens once, before the measured reads.
+	postID := database.NewUUID(database.NewRand(t.seed, database.SetupStream))
+	return t.writePost(ctx, db, dbType, "user0", postID, logger)
 }
 
-func (t *FanOutOnWriteTest) Run(ctx context.Context, db database.DatabaseDriver, concurrency int, duration time.Duration, logger *log.Logger) (*database.Result, error) {
-	result := &database.Result{}
+// fanOutWorker is the per-worker state of FanOutOnWriteTest.
+type fanOutWorker struct {
+	dbType string
+}
 
-	var dbType string
+func (t *FanOutOnWriteTest) InitWorker(ctx context.Context, db database.DatabaseDriver, worker *database.Worker) error {
+	state := &fanOutWorker{}
 	if _, ok := db.(*database.PostgresDriver); ok {
-		dbType = "postgres"
+		state.dbType = "postgres"
 	} else if _, ok := db.(*database.MySQLDriver); ok {
-		dbType = "mysql"
+		state.dbType = "mysql"
 	}
+	worker.State = state
+	return nil
+}
 
-	// Write Phase
-	var wg sync.WaitGroup
-	for i := 0; i < 1; i++ { // Reduced concurrency for diagnostic purposes
-		wg.Add(1)
-		go func(i int) {
-			defer wg.Done()
-			postID := uuid.New().String()
-			userID := fmt.Sprintf("user%d", i%NumUsers)
+// Operation reads user0's timeline. The fan-out write itself is done once in
+// Setup, so the test measures timeline reads.
+func (t *FanOutOnWriteTest) Operation(ctx context.Context, db database.DatabaseDriver, worker *database.Worker) error {
+	state := worker.State.(*fanOutWorker)
+	return t.readTimeline(ctx, db, state.dbType, "user0")
+}
+
+func (t *FanOutOnWriteTest) writePost(ctx context.Context, db database.DatabaseDriver, dbType, userID, postID string, logger *log.Logger) error {
+	// The post is inserted in the same transaction as the fan-out, so that
+	// a retried operation does not leave a post behind from a failed attempt.
+	return db.ExecuteTx(ctx, func(tx interface{}) error {
+		ctx = context.WithValue(ctx, "tx", tx)
 
-			// Insert post outside the transaction
-			postInsertQuery := "INSERT INTO posts (id, user_id, content, created_at) VALUES ($1, $2, $3, $4)"
+		if _, ok := db.(*database.MongoDriver); ok {
+			_, err := db.ExecContext(ctx, "posts", bson.M{"_id": postID, "user_id": userID, "content": "post content", "created_at": time.Now()})
+			if err != nil {
+				logger.Printf("Error inserting post: %v\n", err)
+				return err
+			}
+		} else {
+			query := "INSERT INTO posts (id, user_id, content, created_at) VALUES ($1, $2, $3, $4)"
 			if dbType == "mysql" {
-				postInsertQuery = "INSERT INTO posts (id, user_id, content, created_at) VALUES (?, ?, ?, ?)"
+				query = "INSERT INTO posts (id, user_id, content, created_at) VALUES (?, ?, ?, ?)"
 			}
-			_, err := db.ExecContext(ctx, postInsertQuery, postID, userID, "post content", time.Now())
+			_, err := db.ExecContext(ctx, query, postID, userID, "post content", time.Now())
 			if err != nil {
 				logger.Printf("Error inserting post: %v\n", err)
-				result.Errors++
-				return
+				return err
 			}
+		}
 
-			// Fan-out updates within a transaction with retry mechanism
-			maxRetries := 3
-			for retry := 0; retry < maxRetries; retry++ {
-				err = db.ExecuteTx(ctx, func(tx interface{}) error {
-					ctx = context.WithValue(ctx, "tx", tx)
-
-					if _, ok := db.(*database.MongoDriver); ok {
-						// MongoDB specific fan-out logic (already handled)
-						rows, err := db.QueryContext(ctx, "follows", bson.M{"followee_id": userID})
-						if err != nil {
-							return err
-						}
-						defer rows.Close()
-
-						for rows.Next() {
-							var follow struct {
-								FollowerID string `bson:"follower_id"`
-							}
-							if err := rows.Scan(&follow); err != nil {
-								return err
-							}
-
-							_, err = db.ExecContext(ctx, "timelines", bson.M{"_id": follow.FollowerID}, bson.M{"_id": follow.FollowerID, "$push": bson.M{"post_ids": postID}})
-							if err != nil {
-								return err
-							}
-						}
-					} else { // SQL
-						query := "SELECT follower_id FROM follows WHERE followee_id = $1"
-						if dbType == "mysql" {
-							query = "SELECT follower_id FROM follows WHERE followee_id = ?"
-						}
-						rows, err := db.QueryContext(ctx, query, userID)
-						if err != nil {
-							logger.Printf("Error querying followers: %v\n", err)
-							return err
-						}
-						defer rows.Close()
-
-						for rows.Next() {
-							var followerID string
-							if err := rows.Scan(&followerID); err != nil {
-								logger.Printf("Error scanning follower ID: %v\n", err)
-								return err
-							}
-							// Update JSON array for both MySQL and PostgreSQL
-							var existingPostIDsJSON []byte
-							query := "SELECT post_ids FROM timelines WHERE user_id = $1"
-							if dbType == "mysql" {
-								query = "SELECT post_ids FROM timelines WHERE user_id = ?"
-							}
-							row := db.QueryRowContext(ctx, query, followerID)
-							scanErr := row.Scan(&existingPostIDsJSON)
-							if scanErr != nil && scanErr != sql.ErrNoRows {
-								return scanErr
-							}
-
-							var existingPostIDs []string
-							if len(existingPostIDsJSON) > 0 {
-								json.Unmarshal(existingPostIDsJSON, &existingPostIDs)
-							}
+		if _, ok := db.(*database.MongoDriver); ok {
+			rows, err := db.QueryContext(ctx, "follows", bson.M{"followee_id": userID})
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
 
-							existingPostIDs = append(existingPostIDs, postID)
-							newPostIDsJSON, marshalErr := json.Marshal(existingPostIDs)
-							if marshalErr != nil {
-								return marshalErr
-							}
+			for rows.Next() {
+				var follow struct {
+					FollowerID string `bson:"follower_id"`
+				}
+				if err := rows.Scan(&follow); err != nil {
+					return err
+				}
 
-							updateQuery := "UPDATE timelines SET post_ids = post_ids || $1::jsonb WHERE user_id = $2"
-							if dbType == "mysql" {
-								updateQuery = "UPDATE timelines SET post_ids = ? WHERE user_id = ?"
-							}
-							logger.Printf("Updating timeline for user %s with post %s. New JSON: %s\n", followerID, postID, string(newPostIDsJSON))
-							_, err = db.ExecContext(ctx, updateQuery, newPostIDsJSON, followerID)
-							if err != nil {
-								logger.Printf("Error updating timeline for user %s with post %s: %v\n", followerID, postID, err)
-								return err
-							}
-						}
-					}
-					return nil
-				})
-				if err == nil {
-					break // Transaction successful, break retry loop
-				} else if strings.Contains(err.Error(), "bad connection") {
-					logger.Printf("Retrying transaction due to bad connection: %v\n", err)
-					time.Sleep(100 * time.Millisecond) // Wait before retrying
-				} else {
-					// Other error, no retry
-					break
+				_, err = db.ExecContext(ctx, "timelines", bson.M{"_id": follow.FollowerID}, bson.M{"$push": bson.M{"post_ids": postID}})
+				if err != nil {
+					return err
 				}
 			}
-
+			if err := rows.Err(); err != nil {
+				return err
+			}
+		} else { // SQL
+			query := "SELECT follower_id FROM follows WHERE followee_id = $1"
+			if dbType == "mysql" {
+				query = "SELECT follower_id FROM follows WHERE followee_id = ?"
+			}
+			rows, err := db.QueryContext(ctx, query, userID)
 			if err != nil {
-				result.Errors++
-			} else {
-				result.Operations++
+				logger.Printf("Error querying followers: %v\n", err)
+				return err
 			}
-		}(i)
-	}
-	wg.Wait()
-
-	// Read Phase
-	var readWg sync.WaitGroup
-	histogram := hdrhistogram.New(1, 10000, 3)
-	deadline := time.Now().Add(duration)
-	for i := 0; i < concurrency; i++ {
-		readWg.Add(1)
-		go func() {
-			defer readWg.Done()
-			var err error // Declare err once outside the inner loop
-			for time.Now().Before(deadline) {
-				startTime := time.Now()
-				userID := "user0"
-
-				if _, ok := db.(*database.MongoDriver); ok {
-					row := db.QueryRowContext(ctx, "timelines", bson.M{"_id": userID})
-					var timeline struct {
-						PostIDs []string `bson:"post_ids"`
-					}
-					err = row.Scan(&timeline)
-				} else { // SQL
-					query := "SELECT post_ids FROM timelines WHERE user_id = $1"
-					if dbType == "mysql" {
-						query = "SELECT post_ids FROM timelines WHERE user_id = ?"
-					}
-					row := db.QueryRowContext(ctx, query, userID)
-					var postIDsJSON []byte
-					err = row.Scan(&postIDsJSON)
-					if err == nil && len(postIDsJSON) > 0 {
-						var postIDs []string
-						err = json.Unmarshal(postIDsJSON, &postIDs)
-					}
+			// The followers are read in full first: the transaction's
+			// connection cannot run the updates while the rows are open.
+			var followerIDs []string
+			for rows.Next() {
+				var followerID string
+				if err := rows.Scan(&followerID); err != nil {
+					rows.Close()
+					logger.Printf("Error scanning follower ID: %v\n", err)
+					return err
 				}
+				followerIDs = append(followerIDs, followerID)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				logger.Printf("Error querying followers: %v\n", err)
+				return err
+			}
 
+			// Append the post to each follower's timeline in place.
+			updateQuery := "UPDATE timelines SET post_ids = post_ids || jsonb_build_array($1::text) WHERE user_id = $2"
+			if dbType == "mysql" {
+				updateQuery = "UPDATE timelines SET post_ids = JSON_ARRAY_APPEND(post_ids, '$', ?) WHERE user_id = ?"
+			}
+			for _, followerID := range followerIDs {
+				_, err = db.ExecContext(ctx, updateQuery, postID, followerID)
 				if err != nil {
-					result.Errors++
-				} else {
-					result.Operations++
-					histogram.RecordValue(time.Since(startTime).Milliseconds())
+					logger.Printf("Error updating timeline for user %s with post %s: %v\n", followerID, postID, err)
+					return err
 				}
 			}
-		}()
-	}
-	readWg.Wait()
+		}
+		return nil
+	})
+}
 
-	result.TotalTime = duration
-	result.P95Latency = time.Duration(histogram.ValueAtQuantile(95)) * time.Millisecond
-	result.P99Latency = time.Duration(histogram.ValueAtQuantile(99)) * time.Millisecond
-	result.AverageLatency = time.Duration(histogram.Mean()) * time.Millisecond
-	result.Throughput = float64(result.Operations) / duration.Seconds()
-	if result.Operations+result.Errors > 0 {
-		result.ErrorRate = float64(result.Errors) / float64(result.Operations+result.Errors)
+func (t *FanOutOnWriteTest) readTimeline(ctx context.Context, db database.DatabaseDriver, dbType, userID string) error {
+	var err error
+	if _, ok := db.(*database.MongoDriver); ok {
+		row := db.QueryRowContext(ctx, "timelines", bson.M{"_id": userID})
+		var timeline struct {
+			PostIDs []string `bson:"post_ids"`
+		}
+		err = row.Scan(&timeline)
+	} else { // SQL
+		query := "SELECT post_ids FROM timelines WHERE user_id = $1"
+		if dbType == "mysql" {
+			query = "SELECT post_ids FROM timelines WHERE user_id = ?"
+		}
+		row := db.QueryRowContext(ctx, query, userID)
+		var postIDsJSON []byte
+		err = row.Scan(&postIDsJSON)
+		if err == nil && len(postIDsJSON) > 0 {
+			var postIDs []string
+			err = json.Unmarshal(postIDsJSON, &postIDs)
+		}
 	}
-
-	return result, nil
+	return err
 }
 
 func (t *FanOutOnWriteTest) Teardown(ctx context.Context, db database.DatabaseDriver, logger *log.Logger) error {
@@ -6,14 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"sync"
 	"time"
 
-	"github.com/HdrHistogram/hdrhistogram-go"
-	"github.com/google/uuid"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jackc/pgx/v5/pgconn"
 	"go.mongodb.org/mongo-driver/bson"
-	"github.com/go-sql-driver/mysql"
 )
 
 const (
@@ -22,9 +19,23 @@ const (
 	FollowsPerUser = 100000 / 1000
 )
 
-type JoinOnReadTest struct{}
+type JoinOnReadTest struct {
+	seed     int64
+	progress database.SeedProgress
+}
+
+// Seed sets the seed of the data generated in Setup.
+func (t *JoinOnReadTest) Seed(seed int64) {
+	t.seed = seed
+}
+
+// SetSeedProgress sets the function Setup reports the rows it inserted to.
+func (t *JoinOnReadTest) SetSeedProgress(progress database.SeedProgress) {
+	t.progress = progress
+}
 
 func (t *JoinOnReadTest) Setup(ctx context.Context, db database.DatabaseDriver, logger *log.Logger) error {
+	rng := database.NewRand(t.seed, database.SetupStream)
 	if _, ok := db.(*database.MongoDriver); ok {
 		// MongoDB setup
 		return db.ExecuteTx(ctx, func(tx interface{}) error {
@@ -35,14 +46,16 @@ func (t *JoinOnReadTest) Setup(ctx context.Context, db database.DatabaseDriver,
 				if err != nil {
 					return err
 				}
+				t.progress.Report("users", i+1, NumUsers)
 			}
 			for i := 0; i < NumPosts; i++ {
-				postID := uuid.New().String()
+				postID := database.NewUUID(rng)
 				userID := fmt.Sprintf("user%d", i%NumUsers)
 				_, err := db.ExecContext(ctx, "posts", bson.M{"_id": postID, "user_id": userID, "content": "post content", "created_at": time.Now()})
 				if err != nil {
 					return err
 				}
+				t.progress.Report("posts", i+1, NumPosts)
 			}
 			for i := 0; i < NumFollows; i++ {
 				followerID := fmt.Sprintf("user%d", i%NumUsers)
@@ -51,6 +64,7 @@ func (t *JoinOnReadTest) Setup(ctx context.Context, db database.DatabaseDriver,
 				if err != nil {
 					// Ignore duplicate key errors
 				}
+				t.progress.Report("follows", i+1, NumFollows)
 			}
 			return nil
 		})
@@ -80,10 +94,11 @@ func (t *JoinOnReadTest) Setup(ctx context.Context, db database.DatabaseDriver,
 		if err != nil {
 			return err
 		}
+		t.progress.Report("users", i+1, NumUsers)
 	}
 
 	for i := 0; i < NumPosts; i++ {
-		postID := uuid.New().String()
+		postID := database.NewUUID(rng)
 		userID := fmt.Sprintf("user%d", i%NumUsers)
 		query := "INSERT INTO posts (id, user_id, content, created_at) VALUES ($1, $2, $3, $4)"
 		if _, ok := db.(*database.MySQLDriver); ok {
@@ -93,6 +108,7 @@ func (t *JoinOnReadTest) Setup(ctx context.Context, db database.DatabaseDriver,
 		if err != nil {
 			return err
 		}
+		t.progress.Report("posts", i+1, NumPosts)
 	}
 
 	for i := 0; i < NumFollows; i++ {
@@ -103,75 +119,46 @@ func (t *JoinOnReadTest) Setup(ctx context.Context, db database.DatabaseDriver,
 			query = "INSERT INTO follows (follower_id, followee_id) VALUES (?, ?)"
 		}
 		_, err := db.ExecContext(ctx, query, followerID, followeeID)
-					if err != nil {
-						var pgErr *pgconn.PgError
-						var mysqlErr *mysql.MySQLError
-						if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
-							// do nothing
-						} else if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 { // Duplicate entry for MySQL
-							// do nothing
-						} else {
-							return err
-						}
-					}	}
+		if err != nil {
+			var pgErr *pgconn.PgError
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+				// do nothing
+			} else if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 { // Duplicate entry for MySQL
+				// do nothing
+			} else {
+				return err
+			}
+		}
+		t.progress.Report("follows", i+1, NumFollows)
+	}
 
 	return nil
 }
 
-func (t *JoinOnReadTest) Run(ctx context.Context, db database.DatabaseDriver, concurrency int, duration time.Duration, logger *log.Logger) (*database.Result, error) {
-	var wg sync.WaitGroup
-	histogram := hdrhistogram.New(1, 10000, 3)
-	deadline := time.Now().Add(duration)
-	result := &database.Result{}
-	var mu sync.Mutex
-
-	for i := 0; i < concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for time.Now().Before(deadline) {
-				startTime := time.Now()
-				userID := fmt.Sprintf("user%d", time.Now().UnixNano()%NumUsers)
-				var err error
-				if _, ok := db.(*database.MongoDriver); ok {
-					rows, queryErr := db.QueryContext(ctx, "posts", bson.M{"user_id": bson.M{"$in": getFolloweeIDs(ctx, db, userID)}})
-					err = queryErr
-					if err == nil {
-						rows.Close()
-					}
-				} else {
-					query := "SELECT p.* FROM posts p JOIN follows f ON p.user_id = f.followee_id WHERE f.follower_id = $1"
-					if _, ok := db.(*database.MySQLDriver); ok {
-						query = "SELECT p.* FROM posts p JOIN follows f ON p.user_id = f.followee_id WHERE f.follower_id = ?"
-					}
-					rows, queryErr := db.QueryContext(ctx, query, userID)
-					err = queryErr
-					if err == nil {
-						rows.Close()
-					}
-				}
-
-				mu.Lock()
-				if err != nil {
-					result.Errors++
-				} else {
-					result.Operations++
-					histogram.RecordValue(time.Since(startTime).Milliseconds())
-				}
-				mu.Unlock()
-			}
-		}()
+func (t *JoinOnReadTest) Operation(ctx context.Context, db database.DatabaseDriver, worker *database.Worker) error {
+	userID := fmt.Sprintf("user%d", worker.Rand.Intn(NumUsers))
+	var rows database.Rows
+	var err error
+	if _, ok := db.(*database.MongoDriver); ok {
+		var followeeIDs []string
+		followeeIDs, err = getFolloweeIDs(ctx, db, userID)
+		if err != nil {
+			return err
+		}
+		rows, err = db.QueryContext(ctx, "posts", bson.M{"user_id": bson.M{"$in": followeeIDs}})
+	} else {
+		query := "SELECT p.* FROM posts p JOIN follows f ON p.user_id = f.followee_id WHERE f.follower_id = $1"
+		if _, ok := db.(*database.MySQLDriver); ok {
+			query = "SELECT p.* FROM posts p JOIN follows f ON p.user_id = f.followee_id WHERE f.follower_id = ?"
+		}
+		rows, err = db.QueryContext(ctx, query, userID)
 	}
-
-	wg.Wait()
-
-	result.TotalTime = duration
-	result.Throughput = float64(result.Operations) / duration.Seconds()
-	result.P95Latency = time.Duration(histogram.ValueAtQuantile(95)) * time.Millisecond
-	result.P99Latency = time.Duration(histogram.ValueAtQuantile(99)) * time.Millisecond
-	result.AverageLatency = time.Duration(histogram.Mean()) * time.Millisecond
-
-	return result, nil
+	if err != nil {
+		return err
+	}
+	rows.Close()
+	return rows.Err()
 }
 
 func (t *JoinOnReadTest) Teardown(ctx context.Context, db database.DatabaseDriver, logger *log.Logger) error {
@@ -208,7 +195,7 @@ func (t *JoinOnReadTest) Teardown(ctx context.Context, db database.DatabaseDrive
 	})
 }
 
-func getFolloweeIDs(ctx context.Context, db database.DatabaseDriver, userID string) []string {
+func getFolloweeIDs(ctx context.Context, db database.DatabaseDriver, userID string) ([]string, error) {
 	var rows database.Rows
 	var err error
 	if _, ok := db.(*database.MongoDriver); ok {
@@ -222,7 +209,7 @@ func getFolloweeIDs(ctx context.Context, db database.DatabaseDriver, userID stri
 	}
 
 	if err != nil {
-		return []string{}
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -233,17 +220,17 @@ func getFolloweeIDs(ctx context.Context, db database.DatabaseDriver, userID stri
 				FolloweeID string `bson:"followee_id"`
 			}
 			if err := rows.Scan(&follow); err != nil {
-				continue
+				return nil, err
 			}
 			followeeIDs = append(followeeIDs, follow.FolloweeID)
 		} else {
 			var followeeID string
 			if err := rows.Scan(&followeeID); err != nil {
-				continue
+				return nil, err
 			}
 			followeeIDs = append(followeeIDs, followeeID)
 		}
 	}
 
-	return followeeIDs
-}
\ No newline at end of file
+	return followeeIDs, rows.Err()
+}